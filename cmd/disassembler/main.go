@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 
 	"github.com/kctjohnson/chip8-emu/internal/chip8/disassembler"
 )
@@ -16,5 +17,15 @@ func main() {
 		return
 	}
 
-	disassembler.Disassemble(*inputPath)
+	file, err := os.Open(*inputPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	if err := disassembler.Disassemble(file); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
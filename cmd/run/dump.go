@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+// Colours for each combination of the two XO-CHIP planes, indexed by pixel
+var pngPalette = color.Palette{
+	color.RGBA{0x00, 0x00, 0x00, 0xFF},
+	color.RGBA{0xFF, 0xFF, 0xFF, 0xFF},
+	color.RGBA{0xFF, 0x55, 0x55, 0xFF},
+	color.RGBA{0x55, 0x55, 0xFF, 0xFF},
+}
+
+func writeASCII(w io.Writer, emu *emulator.Emulator) error {
+	_, err := io.WriteString(w, emu.ScreenText())
+	return err
+}
+
+// Writes the screen as a PNG with every pixel scaled up to a scale x scale square
+func writePNG(w io.Writer, emu *emulator.Emulator, scale int) error {
+	width, height := emu.ScreenWidth(), emu.ScreenHeight()
+	img := image.NewPaletted(image.Rect(0, 0, width*scale, height*scale), pngPalette)
+	for x := 0; x < width*scale; x++ {
+		for y := 0; y < height*scale; y++ {
+			img.SetColorIndex(x, y, uint8(emu.Display.Pixel(x/scale, y/scale)&3))
+		}
+	}
+	return png.Encode(w, img)
+}
+
+func writeRegisters(w io.Writer, emu *emulator.Emulator) error {
+	var sb strings.Builder
+	for i, r := range emu.Registers {
+		fmt.Fprintf(&sb, "V%X: 0x%02X\n", i, r)
+	}
+	fmt.Fprintf(&sb, "I: 0x%04X\nPC: 0x%04X\nDT: 0x%02X\nST: 0x%02X\n", emu.I, emu.PC, emu.Delay, emu.SoundDelay)
+	fmt.Fprintf(&sb, "Stack: %v\nFrame: %d\nCycles: %d\n", emu.Stack, emu.Frame, emu.Cycles)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Memory range from Start up to but not including End
+type memRange struct {
+	Start int
+	End   int
+}
+
+// Parses a comma separated list of START:LENGTH or START-END (inclusive) ranges,
+// e.g. "0x300:16,0x200-0x20F"
+func parseMemRanges(s string) ([]memRange, error) {
+	ranges := []memRange{}
+	if strings.TrimSpace(s) == "" {
+		return ranges, nil
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		var r memRange
+		if start, length, found := strings.Cut(entry, ":"); found {
+			a, errA := strconv.ParseUint(start, 0, 32)
+			n, errN := strconv.ParseUint(length, 0, 32)
+			if errA != nil || errN != nil {
+				return nil, fmt.Errorf("invalid memory range %q", entry)
+			}
+			r = memRange{Start: int(a), End: int(a + n)}
+		} else if start, end, found := strings.Cut(entry, "-"); found {
+			a, errA := strconv.ParseUint(start, 0, 32)
+			b, errB := strconv.ParseUint(end, 0, 32)
+			if errA != nil || errB != nil || b < a {
+				return nil, fmt.Errorf("invalid memory range %q", entry)
+			}
+			r = memRange{Start: int(a), End: int(b) + 1}
+		} else {
+			return nil, fmt.Errorf("invalid memory range %q, expected START:LENGTH or START-END", entry)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// Hex dumps the range, 16 bytes a line, stopping at the end of memory
+func writeMemory(w io.Writer, emu *emulator.Emulator, r memRange) error {
+	var sb strings.Builder
+	end := r.End
+	if end > len(emu.Memory) {
+		end = len(emu.Memory)
+	}
+	for addr := r.Start; addr < end; addr += 16 {
+		fmt.Fprintf(&sb, "%04X:", addr)
+		for i := addr; i < addr+16 && i < end; i++ {
+			fmt.Fprintf(&sb, " %02X", emu.Memory[i])
+		}
+		sb.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/audio"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+// Runs a ROM without a terminal UI and dumps the final machine state, for CI and
+// scripted ROM tests
+func main() {
+	romPath := flag.String("in", "", "Input file")
+	platformName := flag.String("platform", "chip8", "Platform to emulate (chip8, schip, xochip), or auto to guess it from the ROM")
+	fontAddress := flag.Uint("font", uint(emulator.DefaultFontAddress), "Address the fonts are loaded at")
+	ipf := flag.Int("ipf", emulator.DefaultInstructionsPerFrame, "Instructions executed per 60Hz frame")
+	seed := flag.Int64("seed", 0, "Seed for the random number generator")
+	quirksName := flag.String("quirks", "", "Quirk preset ("+strings.Join(emulator.QuirkPresetNames(), ", ")+"), defaults to the platform's")
+	frames := flag.Uint64("frames", 600, "Maximum number of frames to run")
+	until := flag.String("until", "", "Stop once PC reaches this address, or this condition holds (e.g. \"V3 == 1\")")
+	keys := flag.String("keys", "", "Key presses as FRAME:KEY[:HOLD], comma separated (e.g. \"30:5,60:a:10\")")
+	inputPath := flag.String("input", "", "Input script to play back, such as one recorded with the emulator's -record")
+	ascii := flag.Bool("ascii", true, "Print the final screen as ASCII art")
+	pngPath := flag.String("png", "", "File the final screen is written to as a PNG")
+	scale := flag.Int("scale", 8, "Size of each pixel in the PNG")
+	regs := flag.Bool("regs", true, "Print the final registers")
+	mem := flag.String("mem", "", "Memory ranges to print as START:LENGTH or START-END, comma separated")
+	wavPath := flag.String("wav", "", "File the buzzer is recorded to as a WAV")
+	flag.Parse()
+
+	if *romPath == "" {
+		fmt.Fprintln(os.Stderr, "Missing input path argument")
+		os.Exit(1)
+	}
+
+	rom, err := os.ReadFile(*romPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	platform, ok := emulator.PlatformByName(*platformName)
+	if *platformName == "auto" {
+		platform = emulator.DetectPlatform(rom)
+	} else if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown platform %q\n", *platformName)
+		os.Exit(1)
+	}
+	input := emulator.NewScriptedInput(nil)
+	opts := []emulator.Option{
+		emulator.WithInput(input),
+		emulator.WithPlatform(platform),
+		emulator.WithFontAddress(chip8.WORD(*fontAddress)),
+		emulator.WithInstructionsPerFrame(*ipf),
+		emulator.WithSeed(*seed),
+	}
+	if *quirksName != "" {
+		quirks, ok := emulator.QuirksByName(*quirksName)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Unknown quirk preset %q\n", *quirksName)
+			os.Exit(1)
+		}
+		opts = append(opts, emulator.WithQuirks(quirks))
+	}
+
+	if *scale < 1 {
+		fmt.Fprintln(os.Stderr, "Scale must be at least 1")
+		os.Exit(1)
+	}
+
+	if err := parseKeys(*keys, input); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *inputPath != "" {
+		if err := readInputScript(*inputPath, input); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	ranges, err := parseMemRanges(*mem)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var wav *audio.WAVWriter
+	if *wavPath != "" {
+		file, err := os.Create(*wavPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+
+		wav, err = audio.NewWAVWriter(file, audio.DefaultSampleRate)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		opts = append(opts, emulator.WithAudio(wav))
+	}
+
+	emu, err := emulator.NewEmulatorFromBytes(rom, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *until != "" {
+		bp, err := parseUntil(*until)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		emu.Breakpoints = append(emu.Breakpoints, bp)
+	}
+
+	runErr := run(emu, *frames)
+	if wav != nil {
+		if err := wav.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if *ascii {
+		writeASCII(os.Stdout, emu)
+	}
+	if *regs {
+		writeRegisters(os.Stdout, emu)
+	}
+	for _, r := range ranges {
+		writeMemory(os.Stdout, emu, r)
+	}
+	if *pngPath != "" {
+		if err := savePNG(*pngPath, emu, *scale); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if errors.Is(runErr, emulator.ErrBreak) {
+		return
+	}
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, runErr)
+		os.Exit(1)
+	}
+	if *until != "" {
+		fmt.Fprintf(os.Stderr, "Stopped after %d frames without reaching %s\n", emu.Frame, *until)
+		os.Exit(2)
+	}
+}
+
+// Runs frames until the frame limit, the ROM exits, or Step returns an error
+// (including the *Break for -until)
+func run(emu *emulator.Emulator, frames uint64) error {
+	for emu.Frame < frames && !emu.Exited {
+		if err := emu.RunFrame(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// An address stops when PC reaches it, anything else is parsed as a condition
+func parseUntil(s string) (emulator.Breakpoint, error) {
+	if addr, err := parseAddress(s); err == nil {
+		return emulator.Breakpoint{Addr: addr}, nil
+	}
+	cond, err := emulator.ParseCondition(s)
+	if err != nil {
+		return emulator.Breakpoint{}, err
+	}
+	return emulator.Breakpoint{AnyAddr: true, Condition: cond}, nil
+}
+
+func parseAddress(s string) (chip8.WORD, error) {
+	addr, err := strconv.ParseUint(strings.ToLower(strings.TrimSpace(s)), 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return chip8.WORD(addr), nil
+}
+
+func savePNG(path string, emu *emulator.Emulator, scale int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := writePNG(file, emu, scale); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+// Adds the presses in s, a comma separated list of FRAME:KEY[:HOLD], e.g. "30:5,60:a:10".
+// HOLD defaults to 1 frame.
+func parseKeys(s string, input *emulator.ScriptedInput) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	for _, entry := range strings.Split(s, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return fmt.Errorf("invalid key press %q, expected FRAME:KEY[:HOLD]", entry)
+		}
+
+		frame, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid frame in key press %q", entry)
+		}
+		key, err := strconv.ParseUint(parts[1], 16, 4)
+		if err != nil {
+			return fmt.Errorf("invalid key in key press %q, expected 0-F", entry)
+		}
+		hold := uint64(1)
+		if len(parts) == 3 {
+			hold, err = strconv.ParseUint(parts[2], 10, 64)
+			if err != nil || hold == 0 {
+				return fmt.Errorf("invalid hold in key press %q", entry)
+			}
+		}
+		input.Press(frame, int(key), hold)
+	}
+	return nil
+}
+
+// Adds the events from an input script, such as one recorded by the TUI
+func readInputScript(path string, input *emulator.ScriptedInput) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	events, err := emulator.ReadInputScript(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	input.Events = append(input.Events, events...)
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+// Rings the terminal bell whenever the buzzer turns on. The bell is written as
+// part of the view rather than straight to the terminal, so it can't interleave
+// with bubbletea's output.
+type terminalBell struct {
+	// Set when the buzzer turned on, until the next tick
+	ring bool
+}
+
+func (b *terminalBell) Buzzer(on bool) {
+	if on {
+		b.ring = true
+	}
+}
+
+func (b *terminalBell) EndFrame() {}
+
+// Text to put before the view, the bell character while it is ringing
+func (b *terminalBell) view() string {
+	if b == nil || !b.ring {
+		return ""
+	}
+	return "\a"
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+const commandHelp = "break ADDR [if COND] | break if COND | watch r|w|rw ADDR | watch REG | delete bN|wN|all | list | until ADDR"
+
+// Runs a debugger command typed into the command line, returning a status message
+func (m Model) runCommand(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	args := fields[1:]
+
+	switch fields[0] {
+	case "b", "break":
+		bp, err := parseBreakpoint(args)
+		if err != nil {
+			return "", err
+		}
+		m.emu.Breakpoints = append(m.emu.Breakpoints, bp)
+		return fmt.Sprintf("b%d: %s", len(m.emu.Breakpoints)-1, bp), nil
+	case "w", "watch":
+		wp, err := parseWatchpoint(args)
+		if err != nil {
+			return "", err
+		}
+		m.emu.Watchpoints = append(m.emu.Watchpoints, wp)
+		return fmt.Sprintf("w%d: %s", len(m.emu.Watchpoints)-1, wp), nil
+	case "d", "delete":
+		return m.deleteCommand(args)
+	case "u", "until":
+		if len(args) != 1 {
+			return "", fmt.Errorf("usage: until ADDR")
+		}
+		addr, err := parseAddress(args[0])
+		if err != nil {
+			return "", err
+		}
+		m.emu.RunTo(addr)
+		speed = true
+		return fmt.Sprintf("Running to 0x%04X", addr), nil
+	case "l", "list":
+		return strings.Join(m.debugPoints(), ", "), nil
+	case "h", "help":
+		return commandHelp, nil
+	}
+	return "", fmt.Errorf("unknown command %q, try: %s", fields[0], commandHelp)
+}
+
+func parseAddress(s string) (chip8.WORD, error) {
+	addr, err := strconv.ParseUint(strings.ToLower(s), 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid address %q", s)
+	}
+	return chip8.WORD(addr), nil
+}
+
+// break ADDR [if COND] or break if COND
+func parseBreakpoint(args []string) (emulator.Breakpoint, error) {
+	var bp emulator.Breakpoint
+	if len(args) == 0 {
+		return bp, fmt.Errorf("usage: break ADDR [if COND] | break if COND")
+	}
+
+	if args[0] == "if" {
+		bp.AnyAddr = true
+	} else {
+		addr, err := parseAddress(args[0])
+		if err != nil {
+			return bp, err
+		}
+		bp.Addr = addr
+		args = args[1:]
+		if len(args) == 0 {
+			return bp, nil
+		}
+		if args[0] != "if" {
+			return bp, fmt.Errorf("expected if, got %q", args[0])
+		}
+	}
+
+	cond, err := emulator.ParseCondition(strings.Join(args[1:], " "))
+	if err != nil {
+		return bp, err
+	}
+	bp.Condition = cond
+	return bp, nil
+}
+
+// watch r|w|rw ADDR or watch REG
+func parseWatchpoint(args []string) (emulator.Watchpoint, error) {
+	var wp emulator.Watchpoint
+	if len(args) == 1 {
+		reg, ok := emulator.ParseRegister(args[0])
+		if !ok {
+			return wp, fmt.Errorf("invalid register %q", args[0])
+		}
+		wp.Kind = emulator.WatchRegister
+		wp.Register = reg
+		return wp, nil
+	}
+	if len(args) != 2 {
+		return wp, fmt.Errorf("usage: watch r|w|rw ADDR | watch REG")
+	}
+
+	switch args[0] {
+	case "r":
+		wp.Kind = emulator.WatchRead
+	case "w":
+		wp.Kind = emulator.WatchWrite
+	case "rw":
+		wp.Kind = emulator.WatchRead | emulator.WatchWrite
+	default:
+		return wp, fmt.Errorf("invalid access %q, expected r, w or rw", args[0])
+	}
+	addr, err := parseAddress(args[1])
+	if err != nil {
+		return wp, err
+	}
+	wp.Addr = int(addr)
+	return wp, nil
+}
+
+// delete bN, wN or all
+func (m Model) deleteCommand(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: delete bN|wN|all")
+	}
+	if args[0] == "all" {
+		m.emu.Breakpoints = nil
+		m.emu.Watchpoints = nil
+		return "Deleted all breakpoints and watchpoints", nil
+	}
+
+	if len(args[0]) < 2 {
+		return "", fmt.Errorf("invalid id %q", args[0])
+	}
+	i, err := strconv.Atoi(args[0][1:])
+	if err != nil {
+		return "", fmt.Errorf("invalid id %q", args[0])
+	}
+	switch args[0][0] {
+	case 'b':
+		if i < 0 || i >= len(m.emu.Breakpoints) {
+			return "", fmt.Errorf("no breakpoint %s", args[0])
+		}
+		m.emu.Breakpoints = append(m.emu.Breakpoints[:i], m.emu.Breakpoints[i+1:]...)
+	case 'w':
+		if i < 0 || i >= len(m.emu.Watchpoints) {
+			return "", fmt.Errorf("no watchpoint %s", args[0])
+		}
+		m.emu.Watchpoints = append(m.emu.Watchpoints[:i], m.emu.Watchpoints[i+1:]...)
+	default:
+		return "", fmt.Errorf("invalid id %q", args[0])
+	}
+	return "Deleted " + args[0], nil
+}
+
+// Lists the breakpoints and watchpoints with the ids used by delete
+func (m Model) debugPoints() []string {
+	points := []string{}
+	for i, bp := range m.emu.Breakpoints {
+		points = append(points, fmt.Sprintf("b%d: %s", i, bp))
+	}
+	for i, wp := range m.emu.Watchpoints {
+		points = append(points, fmt.Sprintf("w%d: %s", i, wp))
+	}
+	return points
+}
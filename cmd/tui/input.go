@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+// Plays back the input script at path instead of reading the keyboard
+func replayInput(path string) (*emulator.ScriptedInput, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events, err := emulator.ReadInputScript(file)
+	if err != nil {
+		return nil, err
+	}
+	return emulator.NewScriptedInput(events), nil
+}
+
+func saveRecording(path string, recorder *emulator.InputRecorder) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := emulator.WriteInputScript(file, recorder.Events); err != nil {
+		return err
+	}
+	return file.Close()
+}
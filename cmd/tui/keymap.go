@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Emulator actions terminal keys can be bound to
+const (
+	actionPause       = "pause"
+	actionStep        = "step"
+	actionStepOver    = "step-over"
+	actionStepOut     = "step-out"
+	actionCursorUp    = "cursor-up"
+	actionCursorDown  = "cursor-down"
+	actionRunToCursor = "run-to-cursor"
+	actionReset       = "reset"
+	actionDebug       = "debug"
+	actionHelp        = "help"
+	actionRender      = "render"
+	actionFaster      = "faster"
+	actionSlower      = "slower"
+	actionTurbo       = "turbo"
+	actionStats       = "stats"
+	actionSave        = "save"
+	actionLoad        = "load"
+	actionSlotPrev    = "slot-prev"
+	actionSlotNext    = "slot-next"
+	actionRewind      = "rewind"
+	actionCommand     = "command"
+	actionLauncher    = "launcher"
+	actionQuit        = "quit"
+	actionNone        = "none" // Unbinds a key
+)
+
+// Actions in the order the help lists them
+var actions = []struct{ name, description string }{
+	{actionPause, "Pause or resume"},
+	{actionStep, "Step one instruction"},
+	{actionStepOver, "Step over a subroutine call"},
+	{actionStepOut, "Step out of the current subroutine"},
+	{actionCursorUp, "Move the run to cursor up"},
+	{actionCursorDown, "Move the run to cursor down"},
+	{actionRunToCursor, "Run to the cursor"},
+	{actionCommand, "Open the debugger command line"},
+	{actionDebug, "Toggle the debug view"},
+	{actionHelp, "Toggle this help"},
+	{actionRender, "Switch to the next renderer"},
+	{actionFaster, "Run more instructions per frame"},
+	{actionSlower, "Run fewer instructions per frame"},
+	{actionTurbo, "Turn the frame limiter off or on"},
+	{actionStats, "Show the IPS/FPS counter"},
+	{actionSlotPrev, "Select the previous save slot"},
+	{actionSlotNext, "Select the next save slot"},
+	{actionSave, "Save to the selected slot"},
+	{actionLoad, "Load from the selected slot"},
+	{actionRewind, "Run time backwards (hold)"},
+	{actionReset, "Reset the machine"},
+	{actionLauncher, "Open the ROM launcher"},
+	{actionQuit, "Quit"},
+}
+
+func isAction(name string) bool {
+	for _, action := range actions {
+		if action.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Maps terminal keys, as named by bubbletea, to a hex keypad key (0-F) or an action
+type keymap map[string]string
+
+func defaultKeymap() keymap {
+	return keymap{
+		"1": "0", "2": "1", "3": "2", "4": "3",
+		"q": "4", "w": "5", "e": "6", "r": "7",
+		"a": "8", "s": "9", "d": "A", "f": "B",
+		"z": "C", "x": "D", "c": "E", "v": "F",
+
+		"p":         actionPause,
+		"n":         actionStep,
+		"o":         actionStepOver,
+		"u":         actionStepOut,
+		"up":        actionCursorUp,
+		"down":      actionCursorDown,
+		"t":         actionRunToCursor,
+		"ctrl+r":    actionReset,
+		"?":         actionDebug,
+		"h":         actionHelp,
+		"g":         actionRender,
+		"+":         actionFaster,
+		"=":         actionFaster,
+		"-":         actionSlower,
+		"tab":       actionTurbo,
+		"i":         actionStats,
+		"f5":        actionSave,
+		"f9":        actionLoad,
+		"[":         actionSlotPrev,
+		"]":         actionSlotNext,
+		"backspace": actionRewind,
+		":":         actionCommand,
+		"l":         actionLauncher,
+		"ctrl+c":    actionQuit,
+	}
+}
+
+// Returns the hex key bound to key, if it is bound to one
+func (k keymap) keypadKey(key string) (int, bool) {
+	binding := k[key]
+	if len(binding) != 1 {
+		return 0, false
+	}
+	hex, err := strconv.ParseUint(binding, 16, 4)
+	return int(hex), err == nil
+}
+
+// Reads "KEY BINDING" lines over the current bindings, where BINDING is a hex
+// key, an action or none. Blank lines and lines starting with # are ignored.
+func (k keymap) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return fmt.Errorf("line %d: expected KEY BINDING", line)
+		}
+		key, binding := fields[0], strings.ToLower(fields[1])
+		if isAction(binding) {
+			k[key] = binding
+			continue
+		}
+		switch {
+		case binding == actionNone:
+			delete(k, key)
+		case len(binding) == 1 && strings.Contains("0123456789abcdef", binding):
+			k[key] = strings.ToUpper(binding)
+		default:
+			return fmt.Errorf("line %d: unknown binding %q", line, fields[1])
+		}
+	}
+	return scanner.Err()
+}
+
+// Reads the keymap file at path over the current bindings. Missing files are
+// skipped unless required.
+func (k keymap) load(path string, required bool) error {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := k.read(file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Builds the keymap from the defaults, then the user's keymap file (or path when
+// given), then the ROM's own ROM_PATH.keymap when a ROM is given
+func loadKeymap(path, romPath string) (keymap, error) {
+	k := defaultKeymap()
+	if path != "" {
+		if err := k.load(path, true); err != nil {
+			return nil, err
+		}
+	} else if dir, err := os.UserConfigDir(); err == nil {
+		if err := k.load(filepath.Join(dir, "gochip", "keymap"), false); err != nil {
+			return nil, err
+		}
+	}
+	if romPath == "" {
+		return k, nil
+	}
+	if err := k.load(romPath+".keymap", false); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Lists the active bindings, the keypad as it is laid out on the machine
+func (k keymap) help() string {
+	keys := map[string][]string{}
+	for key, binding := range k {
+		keys[binding] = append(keys[binding], key)
+	}
+	names := func(binding string) string {
+		bound := keys[binding]
+		if len(bound) == 0 {
+			return "-"
+		}
+		sort.Strings(bound)
+		return strings.Join(bound, "/")
+	}
+
+	help := "KEYPAD\n"
+	for _, row := range []string{"123C", "456D", "789E", "A0BF"} {
+		for _, hex := range row {
+			help += fmt.Sprintf("%c:%-6s ", hex, names(string(hex)))
+		}
+		help += "\n"
+	}
+
+	help += "\nACTIONS\n"
+	for _, action := range actions {
+		help += fmt.Sprintf("%-12s %s\n", names(action.name), action.description)
+	}
+	return help
+}
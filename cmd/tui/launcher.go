@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+// Recently played ROMs remembered
+const maxRecent = 10
+
+// ROMs listed on the launcher at once
+const launcherRows = 20
+
+type romInfo struct {
+	path     string
+	name     string
+	size     int
+	platform emulator.Platform
+	recent   bool
+}
+
+// Lists the recently played ROMs, then the ROMs in a directory
+type launcher struct {
+	dir    string
+	roms   []romInfo
+	cursor int
+	err    error
+}
+
+func newLauncher(dir string) *launcher {
+	l := &launcher{dir: dir}
+	l.roms, l.err = findROMs(dir, readRecent())
+	return l
+}
+
+func isROM(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".ch8" || ext == ".rom"
+}
+
+// Reads the ROMs in recent that still exist, then the ones in dir
+func findROMs(dir string, recent []string) ([]romInfo, error) {
+	roms := []romInfo{}
+	seen := map[string]bool{}
+	add := func(path, name string, isRecent bool) {
+		abs, err := filepath.Abs(path)
+		if err != nil || seen[abs] {
+			return
+		}
+		rom, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		seen[abs] = true
+		roms = append(roms, romInfo{
+			path:     path,
+			name:     name,
+			size:     len(rom),
+			platform: emulator.DetectPlatform(rom),
+			recent:   isRecent,
+		})
+	}
+
+	absDir, _ := filepath.Abs(dir)
+	for _, path := range recent {
+		// Named relative to dir when they are in it
+		name := path
+		if rel, err := filepath.Rel(absDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+		add(path, name, true)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return roms, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name())
+	})
+	for _, entry := range entries {
+		if !entry.IsDir() && isROM(entry.Name()) {
+			add(filepath.Join(dir, entry.Name()), entry.Name(), false)
+		}
+	}
+	return roms, nil
+}
+
+func (l *launcher) view() string {
+	view := fmt.Sprintf("ROMS IN %s\n\n", l.dir)
+	if len(l.roms) == 0 {
+		view += "No .ch8 or .rom files found\n"
+	}
+
+	// Scroll to keep the cursor in view
+	start := 0
+	if l.cursor >= launcherRows {
+		start = l.cursor - launcherRows + 1
+	}
+	for i := start; i < len(l.roms) && i < start+launcherRows; i++ {
+		rom := l.roms[i]
+		cursor := "  "
+		if i == l.cursor {
+			cursor = "> "
+		}
+		recent := ""
+		if rom.recent {
+			recent = "recent"
+		}
+		line := fmt.Sprintf("%s%-40s %6d B  %-6s  %s", cursor, rom.name, rom.size, rom.platform, recent)
+		view += strings.TrimRight(line, " ") + "\n"
+	}
+
+	view += "\nup/down: select  enter: play  esc: back  ctrl+c: quit\n"
+	if l.err != nil {
+		view += l.err.Error() + "\n"
+	}
+	return view
+}
+
+// Moves through the launcher and starts the selected ROM
+func (m Model) updateLauncher(msg tea.KeyMsg) (Model, tea.Cmd) {
+	l := m.launcher
+	switch msg.String() {
+	case "up", "k":
+		if l.cursor > 0 {
+			l.cursor--
+		}
+	case "down", "j":
+		if l.cursor < len(l.roms)-1 {
+			l.cursor++
+		}
+	case "enter":
+		if len(l.roms) == 0 {
+			break
+		}
+		started, err := m.startROM(l.roms[l.cursor].path)
+		if err != nil {
+			l.err = err
+			break
+		}
+		return started, nil
+	case "esc":
+		// Only back to a ROM that is already running
+		if m.emu != nil {
+			m.launcher = nil
+		}
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// Directory the launcher lists, the running ROM's unless -dir was given
+func (m Model) launcherDir() string {
+	if m.romDir != "" {
+		return m.romDir
+	}
+	if m.romPath != "" {
+		return filepath.Dir(m.romPath)
+	}
+	return "."
+}
+
+// Replaces the running ROM with the one at path, keeping the settings it was
+// started with
+func (m Model) startROM(path string) (Model, error) {
+	emu, err := m.newEmulator(path)
+	if err != nil {
+		return m, err
+	}
+	keys, err := loadKeymap(m.keymapPath, path)
+	if err != nil {
+		return m, err
+	}
+
+	m.emu = emu
+	m.romPath = path
+	m.keymap = keys
+	m.launcher = nil
+	m.rewind.clear()
+	m.cursor = 0
+	m.status = ""
+	m.lastTick = time.Now()
+	m.backlog = 0
+	speed = true
+
+	// Losing the recent list isn't worth stopping the ROM over, so only report it
+	m.err = addRecent(path)
+	return m, nil
+}
+
+func recentPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gochip", "recent"), nil
+}
+
+// Reads the recently played ROMs, newest first
+func readRecent() []string {
+	path, err := recentPath()
+	if err != nil {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	recent := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			recent = append(recent, line)
+		}
+	}
+	return recent
+}
+
+// Moves path to the top of the recently played ROMs
+func addRecent(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	recent := []string{abs}
+	for _, p := range readRecent() {
+		if p != abs && len(recent) < maxRecent {
+			recent = append(recent, p)
+		}
+	}
+
+	file, err := recentPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(strings.Join(recent, "\n")+"\n"), 0o644)
+}
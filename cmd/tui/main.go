@@ -1,15 +1,19 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"math/rand"
+	"os"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
 	"github.com/kctjohnson/chip8-emu/internal/chip8/disassembler"
 	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/trace"
 )
 
 var (
@@ -17,12 +21,68 @@ var (
 	displayDebug = false
 )
 
-const FPS = 240
+// Emulated frames per second, which the timers tick at
+const FPS = 60
+
+// How long rewinding continues after a backspace key event. Terminals only send
+// repeated key presses while a key is held, so this has to bridge the delay before
+// the key starts repeating
+const rewindHold = 500 * time.Millisecond
 
 type TickMsg time.Time
 
 type Model struct {
-	emu *emulator.Emulator
+	emu     *emulator.Emulator
+	err     error
+	romPath string
+
+	// Creates the emulator for each ROM with the settings given by the flags
+	newEmulator func(path string) (*emulator.Emulator, error)
+	keymapPath  string
+
+	// ROM launcher, shown instead of the screen while set. emu is nil until the
+	// first ROM is started when the emulator starts on it.
+	launcher *launcher
+	romDir   string
+
+	// Keyboard input, nil when an input script is being played back
+	keypad *emulator.DecayKeypad
+	keymap keymap
+
+	// Shows the active key bindings instead of the screen
+	showHelp bool
+
+	// Index into renderers the screen is drawn with
+	renderer int
+	theme    theme
+	shadows  bool
+
+	// Save state slot used by the save and load keys
+	slot   int
+	status string
+
+	rewind      *rewindBuffer
+	rewindUntil time.Time
+
+	// Terminal bell, nil when -bell=false
+	bell *terminalBell
+
+	// Debugger command line, opened with :
+	commandMode bool
+	command     string
+
+	// Instructions between PC and the run to cursor in the disassembly
+	cursor int
+
+	// Screen refreshes per second. Each refresh runs the emulated frames that have
+	// fallen due since the last, or as many as it can in turbo.
+	refresh  int
+	lastTick time.Time
+	backlog  time.Duration
+	turbo    bool
+
+	meter     *rateMeter
+	showStats bool
 }
 
 func (m Model) Init() tea.Cmd {
@@ -30,8 +90,16 @@ func (m Model) Init() tea.Cmd {
 }
 
 func (m Model) tick() tea.Cmd {
-	fps := FPS
-	if !speed {
+	// Turbo only runs flat out while a ROM is running, the launcher and exited
+	// ROMs refresh at the normal rate
+	if speed && m.turbo && m.launcher == nil && !m.emu.Exited {
+		return func() tea.Msg {
+			return TickMsg(time.Now())
+		}
+	}
+
+	fps := m.refresh
+	if !speed && !m.rewinding() {
 		fps = 1
 	}
 	return tea.Tick(time.Second/time.Duration(fps), func(t time.Time) tea.Msg {
@@ -42,100 +110,257 @@ func (m Model) tick() tea.Cmd {
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case TickMsg:
-		if speed {
-			m.emu.Step()
+		now := time.Time(msg)
+		if m.bell != nil {
+			// The last view has rung it
+			m.bell.ring = false
 		}
+		if m.launcher != nil {
+			m.lastTick = now
+		} else if m.rewinding() {
+			var frames int
+			m, frames = m.dueFrames(now)
+			for i := 0; i < frames && m.err == nil; i++ {
+				var ok bool
+				ok, m.err = m.rewind.pop(m.emu)
+				if !ok {
+					m.status = "Nothing left to rewind"
+					break
+				}
+			}
+		} else if speed {
+			m = m.runFrames(now)
+		} else {
+			m.lastTick = now
+		}
+		m.meter.refresh(now)
 		return m, m.tick()
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "1":
-			m.emu.Inputs[0] = 1
-		case "2":
-			m.emu.Inputs[1] = 1
-		case "3":
-			m.emu.Inputs[2] = 1
-		case "4":
-			m.emu.Inputs[3] = 1
-		case "q":
-			m.emu.Inputs[4] = 1
-		case "w":
-			m.emu.Inputs[5] = 1
-		case "e":
-			m.emu.Inputs[6] = 1
-		case "r":
-			m.emu.Inputs[7] = 1
-		case "a":
-			m.emu.Inputs[8] = 1
-		case "s":
-			m.emu.Inputs[9] = 1
-		case "d":
-			m.emu.Inputs[10] = 1
-		case "f":
-			m.emu.Inputs[11] = 1
-		case "z":
-			m.emu.Inputs[12] = 1
-		case "x":
-			m.emu.Inputs[13] = 1
-		case "c":
-			m.emu.Inputs[14] = 1
-		case "v":
-			m.emu.Inputs[15] = 1
-		case "p":
+		if m.commandMode {
+			return m.updateCommand(msg), nil
+		}
+		if m.launcher != nil {
+			return m.updateLauncher(msg)
+		}
+
+		name := msg.String()
+		if msg.Type == tea.KeySpace {
+			name = "space"
+		}
+
+		// Always offer a way out, whatever the keymap says
+		if name == "ctrl+c" {
+			return m, tea.Quit
+		}
+
+		if key, ok := m.keymap.keypadKey(name); ok {
+			if m.keypad != nil {
+				m.keypad.Press(key)
+			}
+			return m, nil
+		}
+
+		switch m.keymap[name] {
+		case actionSlotPrev:
+			m.slot = (m.slot + 9) % 10
+			m.status = fmt.Sprintf("Slot %d", m.slot)
+		case actionSlotNext:
+			m.slot = (m.slot + 1) % 10
+			m.status = fmt.Sprintf("Slot %d", m.slot)
+		case actionSave:
+			m.status, m.err = m.saveSlot()
+		case actionLoad:
+			m.status, m.err = m.loadSlot()
+		case actionPause:
 			speed = !speed
-		case "ctrl+r":
-			m.emu.CPUReset()
-		case "?":
+		case actionReset:
+			m.err = m.emu.CPUReset()
+			m.rewind.clear()
+		case actionRewind:
+			m.rewindUntil = time.Now().Add(rewindHold)
+		case actionDebug:
 			displayDebug = !displayDebug
-			m.emu.LastTick = time.Now()
-		case "n":
-			m.emu.Step()
-		case "ctrl+c":
+		case actionHelp:
+			m.showHelp = !m.showHelp
+		case actionFaster, actionSlower:
+			m.emu.InstructionsPerFrame = stepIPF(m.emu.InstructionsPerFrame, m.keymap[name] == actionSlower)
+			m.status = fmt.Sprintf("%d instructions per frame", m.emu.InstructionsPerFrame)
+		case actionTurbo:
+			m.turbo = !m.turbo
+			m.status = "Turbo off"
+			if m.turbo {
+				m.status = "Turbo on"
+			}
+		case actionStats:
+			m.showStats = !m.showStats
+		case actionRender:
+			m.renderer = (m.renderer + 1) % len(renderers)
+			m.status = "Renderer: " + renderers[m.renderer].name
+		case actionStep:
+			m = m.run(m.emu.Step)
+		case actionStepOver:
+			m.emu.StepOver()
+			speed = true
+		case actionStepOut:
+			if m.err = m.emu.StepOut(); m.err == nil {
+				speed = true
+			}
+		case actionCursorUp:
+			if m.cursor > -5 {
+				m.cursor--
+			}
+		case actionCursorDown:
+			if m.cursor < 5 {
+				m.cursor++
+			}
+		case actionRunToCursor:
+			m.emu.RunTo(m.emu.PC + chip8.WORD(m.cursor*2))
+			m.cursor = 0
+			speed = true
+		case actionCommand:
+			m.commandMode = true
+			m.command = ""
+		case actionLauncher:
+			m.launcher = newLauncher(m.launcherDir())
+		case actionQuit:
 			return m, tea.Quit
 		}
 	}
 	return m, nil
 }
 
+// Edits and runs the debugger command line
+func (m Model) updateCommand(msg tea.KeyMsg) Model {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.commandMode = false
+		m.status, m.err = m.runCommand(m.command)
+	case tea.KeyEsc:
+		m.commandMode = false
+	case tea.KeyBackspace:
+		if len(m.command) > 0 {
+			m.command = m.command[:len(m.command)-1]
+		}
+	case tea.KeySpace:
+		m.command += " "
+	case tea.KeyRunes:
+		m.command += string(msg.Runes)
+	case tea.KeyCtrlC:
+		m.commandMode = false
+	}
+	return m
+}
+
+// Runs a Step or RunFrame, pausing on faults and breaks so they can be inspected in the debug view
+func (m Model) run(exec func() error) Model {
+	err := exec()
+	if err != nil {
+		speed = false
+	}
+
+	m.err = err
+	if errors.Is(err, emulator.ErrBreak) {
+		m.status = err.Error()
+		m.err = nil
+	}
+	return m
+}
+
+func (m Model) rewinding() bool {
+	return time.Now().Before(m.rewindUntil)
+}
+
+func (m Model) slotPath() string {
+	return fmt.Sprintf("%s.%d.state", m.romPath, m.slot)
+}
+
+func (m Model) saveSlot() (string, error) {
+	file, err := os.Create(m.slotPath())
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := m.emu.SaveState(file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Saved slot %d", m.slot), file.Close()
+}
+
+func (m Model) loadSlot() (string, error) {
+	file, err := os.Open(m.slotPath())
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := m.emu.LoadState(file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Loaded slot %d", m.slot), nil
+}
+
 func (m Model) View() string {
+	if m.launcher != nil {
+		return m.launcher.view()
+	}
+
+	view := m.gameView()
 	if displayDebug {
-		return m.debugView()
+		view = m.debugView()
+	}
+	if m.showHelp {
+		view = m.keymap.help()
+	}
+	if m.showStats {
+		view += fmt.Sprintf("\n%s  %d IPF", m.meter, m.emu.InstructionsPerFrame)
+		if m.turbo {
+			view += "  turbo"
+		}
 	}
-	return m.gameView()
+	if m.status != "" {
+		view += "\n" + m.status
+	}
+	if m.err != nil {
+		view += "\n" + m.err.Error()
+	}
+	if m.commandMode {
+		view += "\n:" + m.command
+	}
+	return m.bell.view() + view
 }
 
 func (m Model) debugView() string {
-	debugData := fmt.Sprintf("CurOp: %X\nDelay: %d\nSound Delay: %d\nI: %X\nPC: %X\n", m.emu.CurrentOpcode, m.emu.Delay, m.emu.SoundDelay, m.emu.I, m.emu.PC)
+	debugData := fmt.Sprintf("CurOp: %X\nDelay: %d\nSound Delay: %d\nI: %X\nPC: %X\nSeed: %d\nFrame: %d\nCycles: %d\n", m.emu.CurrentOpcode, m.emu.Delay, m.emu.SoundDelay, m.emu.I, m.emu.PC, m.emu.Seed, m.emu.Frame, m.emu.Cycles)
+	if m.emu.WaitingForKey {
+		debugData += "Waiting for key\n"
+	}
 	for i, r := range m.emu.Registers {
 		debugData += fmt.Sprintf("Reg%X: %X\n", i, r)
 	}
 	debugData = lipgloss.PlaceHorizontal(25, lipgloss.Top, debugData)
 
-	screen := ""
-	for y := 0; y < 32; y++ {
-		for x := 0; x < 64; x++ {
-			if m.emu.ScreenData[x][y] == 0 {
-				screen += "."
-			} else {
-				screen += "#"
-			}
-		}
-		screen += "\n"
-	}
+	screen := m.emu.ScreenText()
 
 	disassembly := ""
+	cursor := m.emu.PC + chip8.WORD(m.cursor*2)
 	for pc := m.emu.PC - 10; pc <= m.emu.PC+10; pc += 2 {
-		op := m.emu.GetOpcode(pc)
-
 		if pc == m.emu.PC {
 			disassembly += "> "
+		} else if pc == cursor {
+			disassembly += "* "
 		}
-		disassembly += fmt.Sprintf("0x%04X ", pc) + disassembler.DisassembleOpcode(op) + "\n"
+		disassembly += fmt.Sprintf("0x%04X ", pc) + disassembler.DisassembleAt(m.emu, pc) + "\n"
 	}
 	disassembly = lipgloss.PlaceHorizontal(50, lipgloss.Top, disassembly)
 
 	inputs := ""
-	for i, input := range m.emu.Inputs {
-		inputs += fmt.Sprintf("INPUT[%d]: %d\n", i, input)
+	for i := 0; i < 16; i++ {
+		pressed := 0
+		if m.emu.Input.Pressed(i) {
+			pressed = 1
+		}
+		inputs += fmt.Sprintf("INPUT[%d]: %d\n", i, pressed)
 	}
 	inputs = lipgloss.PlaceHorizontal(15, lipgloss.Top, inputs)
 
@@ -143,60 +368,193 @@ func (m Model) debugView() string {
 	for _, s := range m.emu.Stack {
 		stack += fmt.Sprintf("0x%X\n", s)
 	}
+	stack += "\nBREAK/WATCH\n" + strings.Join(m.debugPoints(), "\n")
 
 	return lipgloss.JoinHorizontal(lipgloss.Top, debugData, disassembly, inputs, screen, stack)
 }
 
-var (
-	emptyStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#333388")).
-			Foreground(lipgloss.Color("#444499"))
-	tileStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#333388")).
-			Foreground(lipgloss.Color("#FFFFFF"))
-	shadowStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#333388")).
-			Foreground(lipgloss.Color("#AAAAFF"))
-	renderEmpty  = emptyStyle.Render(".")
-	renderTile   = tileStyle.Render("#")
-	renderShadow = shadowStyle.Render("*")
-)
+func main() {
+	inputPath := flag.String("in", "", "Input file, the launcher is shown when not given")
+	romDir := flag.String("dir", "", "Directory the launcher lists ROMs from, defaults to the running ROM's")
+	platformName := flag.String("platform", "chip8", "Platform to emulate (chip8, schip, xochip), or auto to guess it from each ROM")
+	fontAddress := flag.Uint("font", uint(emulator.DefaultFontAddress), "Address the fonts are loaded at")
+	ipf := flag.Int("ipf", emulator.DefaultInstructionsPerFrame, "Instructions executed per 60Hz frame")
+	rewindSeconds := flag.Int("rewind", 10, "Seconds of gameplay kept for rewinding, 0 to disable")
+	seed := flag.Int64("seed", 0, "Seed for the random number generator, picked from the clock when not set")
+	quirksName := flag.String("quirks", "", "Quirk preset ("+strings.Join(emulator.QuirkPresetNames(), ", ")+"), defaults to the platform's")
+	tracePath := flag.String("trace", "", "File every executed instruction is logged to")
+	traceFrom := flag.Uint("trace-from", 0, "Lowest address logged to the trace")
+	traceTo := flag.Uint("trace-to", 0xFFFF, "Highest address logged to the trace")
+	traceMax := flag.Int("trace-max", 0, "Only keep the last N trace lines, written on exit, 0 to keep every line")
+	keyHold := flag.Uint64("key-hold", 15, "Frames a key stays down after each press, as terminals don't report releases")
+	recordPath := flag.String("record", "", "File the keypad input is recorded to on exit, for playing back with -replay")
+	replayPath := flag.String("replay", "", "Input script played back instead of reading the keyboard")
+	bell := flag.Bool("bell", true, "Ring the terminal bell when the buzzer sounds")
+	keymapPath := flag.String("keymap", "", "Keymap file, read instead of the one in the user config directory")
+	rendererName := flag.String("render", "ascii", "How the screen is drawn ("+strings.Join(rendererNames(), ", ")+")")
+	themeName := flag.String("theme", "default", "Colour theme ("+strings.Join(themeNames(), ", ")+"), or a palette file")
+	colorName := flag.String("color", "auto", "Colours the terminal supports (auto, truecolor, 256, 16, none)")
+	shadows := flag.Bool("shadows", true, "Show the pixels the last sprite collided with as shadows")
+	refresh := flag.Int("refresh", FPS, "Screen refreshes per second (1-60), the emulation runs at 60 frames per second regardless")
+	benchmark := flag.Bool("benchmark", false, "Run as fast as possible with rewinding off, printing the average speed on exit")
+	flag.Parse()
 
-func (m Model) gameView() string {
-	screen := ""
-	for y := 0; y < 32; y++ {
-		for x := 0; x < 64; x++ {
-			switch m.emu.ScreenData[x][y] {
-			case 0:
-				screen += renderEmpty
-			case 1:
-				screen += renderTile
-			case 2:
-				screen += renderShadow
-			}
+	platform, ok := emulator.PlatformByName(*platformName)
+	if !ok && *platformName != "auto" {
+		fmt.Printf("Unknown platform %q", *platformName)
+		return
+	}
+	if *refresh < 1 || *refresh > FPS {
+		fmt.Printf("Refresh rate must be between 1 and %d, got %d", FPS, *refresh)
+		return
+	}
+	if *benchmark {
+		*rewindSeconds = 0
+	}
+
+	renderer, ok := rendererByName(*rendererName)
+	if !ok {
+		fmt.Printf("Unknown renderer %q", *rendererName)
+		return
+	}
+	colors, err := loadTheme(*themeName)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if *colorName != "auto" {
+		profile, ok := colorProfiles[*colorName]
+		if !ok {
+			fmt.Printf("Unknown colour support %q", *colorName)
+			return
 		}
-		screen += "\n"
+		lipgloss.SetColorProfile(profile)
+	}
+	keys, err := loadKeymap(*keymapPath, "")
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
-	return screen
-}
 
-func main() {
-	inputPath := flag.String("in", "", "Input file")
-	flag.Parse()
+	var keypad *emulator.DecayKeypad
+	var input emulator.InputSource
+	if *replayPath != "" {
+		script, err := replayInput(*replayPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		input = script
+	} else {
+		keypad = emulator.NewDecayKeypad(*keyHold)
+		input = keypad
+	}
+	var recorder *emulator.InputRecorder
+	if *recordPath != "" {
+		recorder = emulator.NewInputRecorder(input)
+		input = recorder
+	}
 
-	if *inputPath == "" {
-		fmt.Printf("Missing input path argument")
-		return
+	opts := []emulator.Option{
+		emulator.WithInput(input),
+		emulator.WithFontAddress(chip8.WORD(*fontAddress)),
+		emulator.WithInstructionsPerFrame(*ipf),
+	}
+	var bellAudio *terminalBell
+	if *bell {
+		bellAudio = &terminalBell{}
+		opts = append(opts, emulator.WithAudio(bellAudio))
+	}
+
+	if *quirksName != "" {
+		quirks, ok := emulator.QuirksByName(*quirksName)
+		if !ok {
+			fmt.Printf("Unknown quirk preset %q", *quirksName)
+			return
+		}
+		opts = append(opts, emulator.WithQuirks(quirks))
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			opts = append(opts, emulator.WithSeed(*seed))
+		}
+	})
+
+	var tracer *trace.Tracer
+	if *tracePath != "" {
+		f, err := os.Create(*tracePath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+
+		tracer = trace.NewTracer(f)
+		tracer.From = chip8.WORD(*traceFrom)
+		tracer.To = chip8.WORD(*traceTo)
+		tracer.Limit = *traceMax
+		defer tracer.Flush()
 	}
 
-	rand.Seed(int64(time.Now().Nanosecond()))
+	newEmulator := func(path string) (*emulator.Emulator, error) {
+		rom, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		romPlatform := platform
+		if *platformName == "auto" {
+			romPlatform = emulator.DetectPlatform(rom)
+		}
+		romOpts := append([]emulator.Option{emulator.WithPlatform(romPlatform)}, opts...)
+		emu, err := emulator.NewEmulatorFromBytes(rom, romOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if tracer != nil {
+			tracer.Attach(emu)
+		}
+		return emu, nil
+	}
 
 	model := Model{
-		emu: emulator.NewEmulator(*inputPath),
+		newEmulator: newEmulator,
+		keymapPath:  *keymapPath,
+		romDir:      *romDir,
+
+		keypad:   keypad,
+		keymap:   keys,
+		renderer: renderer,
+		theme:    colors,
+		shadows:  *shadows,
+		rewind:   newRewindBuffer(*rewindSeconds * FPS),
+		bell:     bellAudio,
+
+		refresh:   *refresh,
+		lastTick:  time.Now(),
+		turbo:     *benchmark,
+		meter:     newRateMeter(),
+		showStats: *benchmark,
+	}
+	if *inputPath == "" {
+		model.launcher = newLauncher(model.launcherDir())
+	} else if model, err = model.startROM(*inputPath); err != nil {
+		fmt.Println(err)
+		return
 	}
 
 	p := tea.NewProgram(model, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		panic(err)
 	}
+
+	if *benchmark {
+		fmt.Println(model.meter.summary())
+	}
+
+	if recorder != nil {
+		if err := saveRecording(*recordPath, recorder); err != nil {
+			fmt.Println(err)
+		}
+	}
 }
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+// Pixel shown as a shadow, the others are their XO-CHIP plane bitmask
+const pixelShadow = 4
+
+// Draws the screen's pixels, indexed as pixels[y][x]
+type renderer struct {
+	name   string
+	render func(pixels [][]int, t theme) string
+}
+
+var renderers = []renderer{
+	{"ascii", renderASCII},
+	{"square", renderSquare},
+	{"half", renderHalfBlocks},
+	{"braille", renderBraille},
+}
+
+func rendererByName(name string) (int, bool) {
+	for i, r := range renderers {
+		if r.name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func rendererNames() []string {
+	names := make([]string, len(renderers))
+	for i, r := range renderers {
+		names[i] = r.name
+	}
+	return names
+}
+
+// Draws the screen, with pixels the last sprite collided with shown as shadows
+// when they are enabled and the display keeps a collision overlay
+func (m Model) gameView() string {
+	return renderers[m.renderer].render(m.screenPixels(), m.theme)
+}
+
+func (m Model) screenPixels() [][]int {
+	overlay, _ := m.emu.Display.(emulator.CollisionOverlay)
+	if !m.shadows {
+		overlay = nil
+	}
+
+	pixels := make([][]int, m.emu.ScreenHeight())
+	for y := range pixels {
+		pixels[y] = make([]int, m.emu.ScreenWidth())
+		for x := range pixels[y] {
+			if overlay != nil && overlay.Collided(x, y) {
+				pixels[y][x] = pixelShadow
+			} else {
+				pixels[y][x] = int(m.emu.Display.Pixel(x, y) & 0x3)
+			}
+		}
+	}
+	return pixels
+}
+
+// Rendered strings by text and colours, as rendering styles is slow next to the
+// handful of distinct cells a screen is made of
+type styledCell struct {
+	text   string
+	fg, bg lipgloss.CompleteColor
+}
+
+var styledCells = map[styledCell]string{}
+
+func styled(text string, fg, bg lipgloss.CompleteColor) string {
+	cell := styledCell{text, fg, bg}
+	if s, ok := styledCells[cell]; ok {
+		return s
+	}
+	s := lipgloss.NewStyle().Foreground(fg).Background(bg).Render(text)
+	styledCells[cell] = s
+	return s
+}
+
+// Full block for lit pixels. Lit cells are drawn in the foreground so they still
+// show on terminals without colours.
+func block(pixel int) string {
+	if pixel == 0 {
+		return " "
+	}
+	return "█"
+}
+
+func renderASCII(pixels [][]int, t theme) string {
+	chars := [5]string{".", "#", "#", "#", "*"}
+
+	var screen strings.Builder
+	for _, row := range pixels {
+		for _, pixel := range row {
+			screen.WriteString(styled(chars[pixel], t.pixels[pixel], t.background))
+		}
+		screen.WriteString("\n")
+	}
+	return screen.String()
+}
+
+// Terminal cells are about twice as tall as they are wide, so two make a square pixel
+func renderSquare(pixels [][]int, t theme) string {
+	var screen strings.Builder
+	for _, row := range pixels {
+		for _, pixel := range row {
+			screen.WriteString(styled(block(pixel)+block(pixel), t.blockColor(pixel), t.background))
+		}
+		screen.WriteString("\n")
+	}
+	return screen.String()
+}
+
+// Packs each pair of rows into one line of upper and lower half blocks. Two lit
+// pixels of different colours are drawn as the upper half over the lower one's
+// background.
+func renderHalfBlocks(pixels [][]int, t theme) string {
+	var screen strings.Builder
+	for y := 0; y < len(pixels); y += 2 {
+		for x := range pixels[y] {
+			top, bottom := pixels[y][x], 0
+			if y+1 < len(pixels) {
+				bottom = pixels[y+1][x]
+			}
+
+			switch {
+			case top == bottom:
+				screen.WriteString(styled(block(top), t.blockColor(top), t.background))
+			case bottom == 0:
+				screen.WriteString(styled("▀", t.blockColor(top), t.background))
+			case top == 0:
+				screen.WriteString(styled("▄", t.blockColor(bottom), t.background))
+			default:
+				screen.WriteString(styled("▀", t.blockColor(top), t.blockColor(bottom)))
+			}
+		}
+		screen.WriteString("\n")
+	}
+	return screen.String()
+}
+
+// Bit of each dot in a braille character, indexed by [y][x] within its 2x4 cell
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Packs each 2x4 block of pixels into one braille character. A character only has
+// one colour, so the block takes the colour of its highest pixel: shadows over
+// both planes over plane 2 over plane 1.
+func renderBraille(pixels [][]int, t theme) string {
+	var screen strings.Builder
+	for y := 0; y < len(pixels); y += 4 {
+		for x := 0; x < len(pixels[y]); x += 2 {
+			dots, color := rune(0), 0
+			for dy := 0; dy < 4 && y+dy < len(pixels); dy++ {
+				for dx := 0; dx < 2 && x+dx < len(pixels[y+dy]); dx++ {
+					if pixel := pixels[y+dy][x+dx]; pixel != 0 {
+						dots |= brailleDots[dy][dx]
+						if pixel > color {
+							color = pixel
+						}
+					}
+				}
+			}
+			screen.WriteString(styled(string(0x2800+dots), t.blockColor(color), t.background))
+		}
+		screen.WriteString("\n")
+	}
+	return screen.String()
+}
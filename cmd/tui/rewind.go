@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+// Ring buffer of compressed per-frame save states, newest last
+type rewindBuffer struct {
+	frames [][]byte
+	start  int
+	count  int
+
+	buf bytes.Buffer
+	zw  *flate.Writer
+}
+
+func newRewindBuffer(capacity int) *rewindBuffer {
+	zw, _ := flate.NewWriter(nil, flate.BestSpeed)
+	return &rewindBuffer{
+		frames: make([][]byte, capacity),
+		zw:     zw,
+	}
+}
+
+// Snapshots the emulator, overwriting the oldest frame once the buffer is full
+func (r *rewindBuffer) push(emu *emulator.Emulator) error {
+	if len(r.frames) == 0 {
+		return nil
+	}
+
+	r.buf.Reset()
+	r.zw.Reset(&r.buf)
+	if err := emu.SaveState(r.zw); err != nil {
+		return err
+	}
+	if err := r.zw.Close(); err != nil {
+		return err
+	}
+
+	i := (r.start + r.count) % len(r.frames)
+	r.frames[i] = append(r.frames[i][:0], r.buf.Bytes()...)
+	if r.count < len(r.frames) {
+		r.count++
+	} else {
+		r.start = (r.start + 1) % len(r.frames)
+	}
+	return nil
+}
+
+// Restores the newest frame into the emulator and drops it from the buffer.
+// Returns false when there is nothing left to rewind to.
+func (r *rewindBuffer) pop(emu *emulator.Emulator) (bool, error) {
+	if r.count == 0 {
+		return false, nil
+	}
+	r.count--
+	frame := r.frames[(r.start+r.count)%len(r.frames)]
+
+	zr := flate.NewReader(bytes.NewReader(frame))
+	defer zr.Close()
+	if err := emu.LoadState(zr); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *rewindBuffer) clear() {
+	r.start = 0
+	r.count = 0
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const frameTime = time.Second / FPS
+
+// Longest backlog of emulated time run in one tick. After a pause or a stall
+// the rest is dropped, rather than fast forwarding to catch up.
+const maxBacklog = time.Second / 4
+
+// Instructions per frame the speed keys step through
+var ipfSteps = []int{1, 2, 3, 5, 7, 10, 15, 20, 30, 50, 75, 100, 150, 200, 300, 500, 1000}
+
+// Returns the next step above ipf, or below it when down is set
+func stepIPF(ipf int, down bool) int {
+	if down {
+		for i := len(ipfSteps) - 1; i >= 0; i-- {
+			if ipfSteps[i] < ipf {
+				return ipfSteps[i]
+			}
+		}
+		return ipfSteps[0]
+	}
+	for _, step := range ipfSteps {
+		if step > ipf {
+			return step
+		}
+	}
+	return ipfSteps[len(ipfSteps)-1]
+}
+
+// Counts the emulated frames falling due since the last tick, keeping the emulation
+// at 60 frames per second whatever the refresh rate
+func (m Model) dueFrames(now time.Time) (Model, int) {
+	m.backlog += now.Sub(m.lastTick)
+	m.lastTick = now
+	if m.backlog > maxBacklog {
+		m.backlog = frameTime
+	}
+
+	n := int(m.backlog / frameTime)
+	m.backlog -= time.Duration(n) * frameTime
+	return m, n
+}
+
+// Runs the frames due this tick, or as many as fit in one refresh in turbo.
+// Nothing runs once the ROM has exited, so no rewind snapshots are taken of it
+func (m Model) runFrames(now time.Time) Model {
+	m, due := m.dueFrames(now)
+	budget := time.Second / time.Duration(m.refresh)
+
+	cycles := m.emu.Cycles
+	frames := 0
+	for speed && m.err == nil && !m.emu.Exited {
+		if m.turbo {
+			if frames > 0 && time.Since(now) >= budget {
+				break
+			}
+		} else if frames >= due {
+			break
+		}
+
+		if m.err = m.rewind.push(m.emu); m.err != nil {
+			break
+		}
+		m = m.run(m.emu.RunFrame)
+		frames++
+	}
+
+	// Resets and breaks can leave Cycles below where it started
+	if m.emu.Cycles > cycles {
+		m.meter.instructions += m.emu.Cycles - cycles
+	}
+	m.meter.frames += uint64(frames)
+	return m
+}
+
+// Measures the instructions, emulated frames and screen refreshes per second over
+// one second windows
+type rateMeter struct {
+	start        time.Time
+	instructions uint64
+	frames       uint64
+	refreshes    uint64
+
+	// Rates over the last full window
+	ips, fps, refreshRate float64
+
+	// Totals since the first window, for the benchmark summary
+	began                          time.Time
+	totalInstructions, totalFrames uint64
+}
+
+func newRateMeter() *rateMeter {
+	now := time.Now()
+	return &rateMeter{start: now, began: now}
+}
+
+// Counts a screen refresh, closing the window once a second has passed
+func (r *rateMeter) refresh(now time.Time) {
+	r.refreshes++
+
+	elapsed := now.Sub(r.start).Seconds()
+	if elapsed < 1 {
+		return
+	}
+	r.ips = float64(r.instructions) / elapsed
+	r.fps = float64(r.frames) / elapsed
+	r.refreshRate = float64(r.refreshes) / elapsed
+
+	r.totalInstructions += r.instructions
+	r.totalFrames += r.frames
+	r.start, r.instructions, r.frames, r.refreshes = now, 0, 0, 0
+}
+
+func (r *rateMeter) String() string {
+	return fmt.Sprintf("%.0f IPS  %.1f FPS  %.1f Hz refresh", r.ips, r.fps, r.refreshRate)
+}
+
+// Averages since the meter was made
+func (r *rateMeter) summary() string {
+	instructions := r.totalInstructions + r.instructions
+	frames := r.totalFrames + r.frames
+	elapsed := time.Since(r.began)
+	return fmt.Sprintf("Ran %d instructions and %d frames in %s: %.0f IPS, %.1f FPS",
+		instructions, frames, elapsed.Round(time.Millisecond),
+		float64(instructions)/elapsed.Seconds(), float64(frames)/elapsed.Seconds())
+}
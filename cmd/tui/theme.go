@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Colours the screen is drawn in. Each colour carries its own 256 and 16 colour
+// fallbacks, as converting the truecolour one often merges neighbouring shades.
+type theme struct {
+	background lipgloss.CompleteColor
+
+	// Indexed by pixel. Unlit pixels only use theirs for the ascii renderer's dots,
+	// the block renderers leave them as background.
+	pixels [5]lipgloss.CompleteColor
+}
+
+func color(trueColor, ansi256, ansi string) lipgloss.CompleteColor {
+	return lipgloss.CompleteColor{TrueColor: trueColor, ANSI256: ansi256, ANSI: ansi}
+}
+
+var themes = map[string]theme{
+	"default": {
+		background: color("#333388", "60", "4"),
+		pixels: [5]lipgloss.CompleteColor{
+			color("#444499", "61", "12"),
+			color("#FFFFFF", "231", "15"),
+			color("#FF8844", "209", "9"),
+			color("#FFEE44", "227", "11"),
+			color("#AAAAFF", "147", "14"),
+		},
+	},
+	"green": {
+		background: color("#0A1A0A", "233", "0"),
+		pixels: [5]lipgloss.CompleteColor{
+			color("#12301A", "22", "2"),
+			color("#33FF66", "83", "10"),
+			color("#1FAA44", "35", "2"),
+			color("#AAFFAA", "157", "15"),
+			color("#1A6630", "29", "6"),
+		},
+	},
+	"amber": {
+		background: color("#1A0F00", "233", "0"),
+		pixels: [5]lipgloss.CompleteColor{
+			color("#2E1C00", "58", "3"),
+			color("#FFB000", "214", "11"),
+			color("#CC7A00", "172", "3"),
+			color("#FFE0A0", "223", "15"),
+			color("#7A4A00", "94", "1"),
+		},
+	},
+	"lcd": {
+		background: color("#9BBC0F", "142", "10"),
+		pixels: [5]lipgloss.CompleteColor{
+			color("#8BAC0F", "106", "2"),
+			color("#0F380F", "22", "0"),
+			color("#306230", "65", "2"),
+			color("#204820", "236", "8"),
+			color("#6A8A1F", "100", "3"),
+		},
+	},
+	"contrast": {
+		background: color("#000000", "16", "0"),
+		pixels: [5]lipgloss.CompleteColor{
+			color("#262626", "235", "8"),
+			color("#FFFFFF", "231", "15"),
+			color("#00FFFF", "51", "14"),
+			color("#FFFF00", "226", "11"),
+			color("#FF00FF", "201", "13"),
+		},
+	},
+}
+
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Colour a pixel fills a whole block with
+func (t theme) blockColor(pixel int) lipgloss.CompleteColor {
+	if pixel == 0 {
+		return t.background
+	}
+	return t.pixels[pixel]
+}
+
+// Palette file colours, by the name they are set with
+var paletteColors = map[string]func(t *theme) *lipgloss.CompleteColor{
+	"background": func(t *theme) *lipgloss.CompleteColor { return &t.background },
+	"unlit":      func(t *theme) *lipgloss.CompleteColor { return &t.pixels[0] },
+	"plane1":     func(t *theme) *lipgloss.CompleteColor { return &t.pixels[1] },
+	"plane2":     func(t *theme) *lipgloss.CompleteColor { return &t.pixels[2] },
+	"both":       func(t *theme) *lipgloss.CompleteColor { return &t.pixels[3] },
+	"shadow":     func(t *theme) *lipgloss.CompleteColor { return &t.pixels[pixelShadow] },
+}
+
+var hexColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// Reads "NAME #RRGGBB [ANSI256 [ANSI]]" lines of a palette file over the theme's
+// colours, where the optional fallbacks are 256 colour (0-255) and 16 colour (0-15)
+// numbers. Fallbacks left out are converted from the truecolour. Blank lines and
+// lines starting with # are ignored.
+func (t *theme) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 2 || len(fields) > 4 {
+			return fmt.Errorf("line %d: expected NAME #RRGGBB [ANSI256 [ANSI]]", line)
+		}
+		target, ok := paletteColors[strings.ToLower(fields[0])]
+		if !ok {
+			return fmt.Errorf("line %d: unknown colour %q", line, fields[0])
+		}
+		if !hexColor.MatchString(fields[1]) {
+			return fmt.Errorf("line %d: invalid colour %q, expected #RRGGBB", line, fields[1])
+		}
+
+		c := color(fields[1], fields[1], fields[1])
+		if len(fields) > 2 {
+			n, err := strconv.ParseUint(fields[2], 10, 8)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid 256 colour %q", line, fields[2])
+			}
+			c.ANSI256 = fields[2]
+			if n < 16 {
+				c.ANSI = fields[2]
+			}
+		}
+		if len(fields) > 3 {
+			if n, err := strconv.ParseUint(fields[3], 10, 8); err != nil || n > 15 {
+				return fmt.Errorf("line %d: invalid 16 colour %q", line, fields[3])
+			}
+			c.ANSI = fields[3]
+		}
+		*target(t) = c
+	}
+	return scanner.Err()
+}
+
+// Finds the theme called name: a built in one, a palette file at the path name,
+// or one saved as <UserConfigDir>/gochip/themes/NAME. Palette files start from
+// the default theme.
+func loadTheme(name string) (theme, error) {
+	if t, ok := themes[name]; ok {
+		return t, nil
+	}
+
+	path := name
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return theme{}, fmt.Errorf("unknown theme %q", name)
+		}
+		path = filepath.Join(dir, "gochip", "themes", name)
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return theme{}, fmt.Errorf("unknown theme %q", name)
+	}
+	if err != nil {
+		return theme{}, err
+	}
+	defer file.Close()
+
+	t := themes["default"]
+	if err := t.read(file); err != nil {
+		return theme{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Colour profiles -color can force, for terminals that misreport what they support
+var colorProfiles = map[string]termenv.Profile{
+	"truecolor": termenv.TrueColor,
+	"256":       termenv.ANSI256,
+	"16":        termenv.ANSI,
+	"none":      termenv.Ascii,
+}
@@ -0,0 +1,40 @@
+package audio
+
+const (
+	// Frequency of the buzzer, as on most CHIP-8 interpreters
+	DefaultFrequency  = 440
+	DefaultSampleRate = 44100
+)
+
+// SquareWave generates signed 16 bit PCM samples of a square wave
+type SquareWave struct {
+	SampleRate int
+	Frequency  float64
+	Volume     int16 // Amplitude of the wave
+
+	phase float64 // Position in the current period, 0 to 1
+}
+
+func NewSquareWave(sampleRate int, frequency float64) *SquareWave {
+	return &SquareWave{
+		SampleRate: sampleRate,
+		Frequency:  frequency,
+		Volume:     0x2000,
+	}
+}
+
+// Fills samples with the wave, carrying on from where the previous call stopped
+func (s *SquareWave) Read(samples []int16) {
+	step := s.Frequency / float64(s.SampleRate)
+	for i := range samples {
+		if s.phase < 0.5 {
+			samples[i] = s.Volume
+		} else {
+			samples[i] = -s.Volume
+		}
+		s.phase += step
+		if s.phase >= 1 {
+			s.phase -= 1
+		}
+	}
+}
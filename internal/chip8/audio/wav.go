@@ -0,0 +1,120 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Size of the RIFF and fmt chunks before the sample data
+const wavHeaderSize = 44
+
+// WAVWriter records the buzzer to a mono 16 bit WAV file, implementing
+// emulator.Audio. Each 60Hz frame the buzzer sounded in is written as a square
+// wave and every other frame as silence.
+type WAVWriter struct {
+	w          io.WriteSeeker
+	sampleRate int
+	wave       *SquareWave
+
+	on      bool
+	sounded bool // The buzzer was on at some point in the current frame
+	frames  uint64
+	samples int
+	buf     []int16
+	err     error
+}
+
+// Starts a WAV file on w. Close must be called to fill in the header.
+func NewWAVWriter(w io.WriteSeeker, sampleRate int) (*WAVWriter, error) {
+	ww := &WAVWriter{
+		w:          w,
+		sampleRate: sampleRate,
+		wave:       NewSquareWave(sampleRate, DefaultFrequency),
+	}
+	if err := ww.writeHeader(); err != nil {
+		return nil, err
+	}
+	return ww, nil
+}
+
+func (ww *WAVWriter) Buzzer(on bool) {
+	ww.on = on
+	if on {
+		ww.sounded = true
+	}
+}
+
+func (ww *WAVWriter) EndFrame() {
+	if ww.err != nil {
+		return
+	}
+
+	// Spread the remainder when the sample rate isn't a multiple of 60
+	n := int(uint64(ww.sampleRate)*(ww.frames+1)/60 - uint64(ww.sampleRate)*ww.frames/60)
+	ww.frames++
+	if cap(ww.buf) < n {
+		ww.buf = make([]int16, n)
+	}
+	buf := ww.buf[:n]
+
+	if ww.sounded {
+		ww.wave.Read(buf)
+	} else {
+		for i := range buf {
+			buf[i] = 0
+		}
+	}
+	ww.sounded = ww.on
+
+	ww.err = binary.Write(ww.w, binary.LittleEndian, buf)
+	ww.samples += n
+}
+
+// Fills in the header with the final length. The underlying writer is left open.
+func (ww *WAVWriter) Close() error {
+	if ww.err != nil {
+		return ww.err
+	}
+	if _, err := ww.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := ww.writeHeader(); err != nil {
+		return err
+	}
+	_, err := ww.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+func (ww *WAVWriter) writeHeader() error {
+	dataSize := uint32(ww.samples * 2)
+	header := struct {
+		RIFF          [4]byte
+		Size          uint32
+		WAVE          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		Format        uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF:          [4]byte{'R', 'I', 'F', 'F'},
+		Size:          wavHeaderSize - 8 + dataSize,
+		WAVE:          [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:           [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		Format:        1, // PCM
+		Channels:      1,
+		SampleRate:    uint32(ww.sampleRate),
+		ByteRate:      uint32(ww.sampleRate * 2),
+		BlockAlign:    2,
+		BitsPerSample: 16,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      dataSize,
+	}
+	return binary.Write(ww.w, binary.LittleEndian, header)
+}
@@ -0,0 +1,60 @@
+package audio
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buzzer.wav")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	ww, err := NewWAVWriter(file, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ww.EndFrame()
+	ww.Buzzer(true)
+	ww.Buzzer(false) // A buzz shorter than a frame still sounds for the frame
+	ww.EndFrame()
+	ww.EndFrame()
+	if err := ww.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const frameSamples = 48000 / 60
+	if len(data) != wavHeaderSize+3*frameSamples*2 {
+		t.Fatalf("file is %d bytes, want %d", len(data), wavHeaderSize+3*frameSamples*2)
+	}
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		t.Fatalf("bad header %q", data[:12])
+	}
+	if size := binary.LittleEndian.Uint32(data[40:44]); size != 3*frameSamples*2 {
+		t.Errorf("data chunk size %d, want %d", size, 3*frameSamples*2)
+	}
+
+	// Only the second frame has sound
+	samples := data[wavHeaderSize:]
+	for frame := 0; frame < 3; frame++ {
+		silent := true
+		for i := frame * frameSamples * 2; i < (frame+1)*frameSamples*2; i++ {
+			if samples[i] != 0 {
+				silent = false
+				break
+			}
+		}
+		if silent != (frame != 1) {
+			t.Errorf("frame %d silent = %v", frame, silent)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package chip8test runs ROMs in tests without a frontend and compares the screen
+// against golden text files. Run the tests with -update to regenerate the golden files.
+package chip8test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/compiler"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/parser"
+)
+
+var update = flag.Bool("update", false, "Rewrite golden screen files instead of comparing against them")
+
+// Machine drives an emulator for a test, failing the test on any emulator error
+type Machine struct {
+	Emu *emulator.Emulator
+
+	t     testing.TB
+	input *emulator.ScriptedInput
+}
+
+// Loads the ROM at path. Unless options say otherwise the random seed is 0, so
+// runs are repeatable. Press has no effect if the options replace the input.
+func Load(t testing.TB, path string, opts ...emulator.Option) *Machine {
+	t.Helper()
+	rom, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return LoadBytes(t, rom, opts...)
+}
+
+func LoadBytes(t testing.TB, rom []byte, opts ...emulator.Option) *Machine {
+	t.Helper()
+	input := emulator.NewScriptedInput(nil)
+	opts = append([]emulator.Option{emulator.WithSeed(0), emulator.WithInput(input)}, opts...)
+	emu, err := emulator.NewEmulatorFromBytes(rom, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Machine{
+		Emu:   emu,
+		t:     t,
+		input: input,
+	}
+}
+
+// Compiles assembly source with the repository's compiler, for tests of small programs
+func Assemble(t testing.TB, source string) []byte {
+	t.Helper()
+	p := parser.NewParser(source)
+	p.ReadTokens()
+	rom := compiler.NewCompiler(p.GetTokens()).Compile()
+	if len(rom) == 0 {
+		t.Fatal("assembled program is empty")
+	}
+	return rom
+}
+
+// Holds key (0-F) down for the next frames frames
+func (m *Machine) Press(key int, frames uint64) {
+	m.input.Press(m.Emu.Frame, key, frames)
+}
+
+// Runs frames frames, or fewer if the ROM exits
+func (m *Machine) RunFrames(frames uint64) {
+	m.t.Helper()
+	end := m.Emu.Frame + frames
+	for m.Emu.Frame < end && !m.Emu.Exited {
+		if err := m.Emu.RunFrame(); err != nil {
+			m.t.Fatalf("frame %d: %v", m.Emu.Frame, err)
+		}
+	}
+}
+
+// Runs until PC reaches addr, failing the test if that takes more than maxFrames frames
+func (m *Machine) RunUntil(addr chip8.WORD, maxFrames uint64) {
+	m.t.Helper()
+	bp := emulator.Breakpoint{Addr: addr}
+	m.Emu.Breakpoints = append(m.Emu.Breakpoints, bp)
+	defer func() {
+		m.Emu.Breakpoints = m.Emu.Breakpoints[:len(m.Emu.Breakpoints)-1]
+	}()
+
+	end := m.Emu.Frame + maxFrames
+	for m.Emu.Frame < end && !m.Emu.Exited {
+		err := m.Emu.RunFrame()
+		if errors.Is(err, emulator.ErrBreak) {
+			return
+		}
+		if err != nil {
+			m.t.Fatalf("frame %d: %v", m.Emu.Frame, err)
+		}
+	}
+	m.t.Fatalf("PC did not reach 0x%04X within %d frames", addr, maxFrames)
+}
+
+// Compares the screen against testdata/name.golden, or writes it there when
+// the tests are run with -update
+func (m *Machine) MatchScreen(name string) {
+	m.t.Helper()
+	MatchGolden(m.t, name, m.Emu.ScreenText())
+}
+
+// Compares got against testdata/name.golden, or writes it there when the tests
+// are run with -update
+func MatchGolden(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if string(want) != got {
+		t.Errorf("%s does not match the golden file %s\n%s", name, path, diffLines(string(want), got))
+	}
+}
+
+// Lists the lines that differ between want and got
+func diffLines(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+
+	var sb strings.Builder
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			fmt.Fprintf(&sb, "line %d:\n  want %s\n  got  %s\n", i+1, w, g)
+		}
+	}
+	return sb.String()
+}
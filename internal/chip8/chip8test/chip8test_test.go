@@ -0,0 +1,57 @@
+package chip8test
+
+import (
+	"testing"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+func TestAssembledDigits(t *testing.T) {
+	rom := Assemble(t, `
+MOV REG[0x0], 0xA
+MOV REG[0x1], 2
+MOV REG[0x2], 3
+FX29 REG[0x0]
+DRW REG[0x1], REG[0x2], 5
+ADD REG[0x0], 1
+ADD REG[0x1], 6
+SEQ REG[0x0], 0x10
+JMP 0x206
+Done:
+JMP Done
+`)
+	m := LoadBytes(t, rom)
+	m.RunFrames(10)
+	m.MatchScreen("digits")
+}
+
+func TestHiResExit(t *testing.T) {
+	rom := Assemble(t, `
+HIGH
+MOV REG[0x0], 8
+FX30 REG[0x0]
+MOV REG[0x1], 100
+MOV REG[0x2], 40
+DRW REG[0x1], REG[0x2], 10
+EXIT
+`)
+	m := LoadBytes(t, rom, emulator.WithPlatform(emulator.PlatformSChip))
+	m.RunFrames(10)
+	if !m.Emu.Exited {
+		t.Fatal("expected the ROM to exit")
+	}
+	m.MatchScreen("hires_exit")
+}
+
+func TestSpaceshipKeys(t *testing.T) {
+	m := Load(t, "../../../docs/example/spaceship.rom")
+	m.RunFrames(5)
+	m.MatchScreen("spaceship_start")
+
+	// Each key draws the ship facing a different way
+	for _, key := range []int{5, 8, 9, 10} {
+		m.Press(key, 2)
+		m.RunFrames(5)
+		m.MatchScreen("spaceship_key" + string("0123456789ABCDEF"[key]))
+	}
+}
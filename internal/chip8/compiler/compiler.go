@@ -57,6 +57,15 @@ var OpcodeMap = map[mapKey]int{
 	{Type: parser.FX33, Format: CMD_REG}:        0xF033,
 	{Type: parser.FX55, Format: CMD_REG}:        0xF055,
 	{Type: parser.FX65, Format: CMD_REG}:        0xF065,
+	{Type: parser.SCD, Format: CMD_VAL}:         0x00C0,
+	{Type: parser.SCR, Format: CMD}:             0x00FB,
+	{Type: parser.SCL, Format: CMD}:             0x00FC,
+	{Type: parser.EXIT, Format: CMD}:            0x00FD,
+	{Type: parser.LOW, Format: CMD}:             0x00FE,
+	{Type: parser.HIGH, Format: CMD}:            0x00FF,
+	{Type: parser.FX30, Format: CMD_REG}:        0xF030,
+	{Type: parser.FX75, Format: CMD_REG}:        0xF075,
+	{Type: parser.FX85, Format: CMD_REG}:        0xF085,
 }
 
 func (c Compiler) Compile() []byte {
@@ -141,6 +150,10 @@ func ParseCMD(opcode int) []byte {
 
 func ParseCMD_VAL(opcode int, inst Instruction) int {
 	val := valueToInt(inst.Tokens[1])
+	// SCD only has room for a nibble
+	if opcode == 0x00C0 {
+		return opcode | (0xF & val)
+	}
 	op := opcode | (0xFFF & val)
 	return op
 }
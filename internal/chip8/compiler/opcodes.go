@@ -337,6 +337,29 @@ func (is *InstructionSet) parse(tokens []parser.Token) {
 				Offset: curOffset,
 			})
 			curOffset += 0x2
+		case parser.SCD:
+			is.Instructions = append(is.Instructions, Instruction{
+				Format: CMD_VAL,
+				Tokens: tokens[i : i+2],
+				Offset: curOffset,
+			})
+			curOffset += 0x2
+			i++
+		case parser.SCR, parser.SCL, parser.EXIT, parser.LOW, parser.HIGH:
+			is.Instructions = append(is.Instructions, Instruction{
+				Format: CMD,
+				Tokens: []parser.Token{curToken},
+				Offset: curOffset,
+			})
+			curOffset += 0x2
+		case parser.FX30, parser.FX75, parser.FX85:
+			is.Instructions = append(is.Instructions, Instruction{
+				Format: CMD_REG,
+				Tokens: tokens[i : i+5],
+				Offset: curOffset,
+			})
+			curOffset += 0x2
+			i += 4
 		}
 	}
 }
@@ -2,17 +2,37 @@ package disassembler
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/kctjohnson/chip8-emu/internal/chip8"
 	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
 )
 
-func Disassemble(gameFilePath string) {
-	emu := emulator.NewEmulator(gameFilePath)
+// Prints the disassembly of the ROM read from r until it runs into a block of zeroes
+func Disassemble(r io.Reader) error {
+	emu, err := emulator.NewEmulatorFromReader(r, emulator.WithPlatform(emulator.PlatformXOChip))
+	if err != nil {
+		return err
+	}
 
 	zeroCounter := 0
 	for zeroCounter < 5 {
-		op := emu.GetNextOpcode()
+		op, err := emu.GetNextOpcode()
+		if err != nil {
+			// Ran off the end of memory
+			return nil
+		}
+
+		// XO-CHIP's F000 NNNN takes the address from the word after it
+		if op == 0xF000 {
+			if _, err := emu.GetNextOpcode(); err != nil {
+				fmt.Print(DisassembleOpcode(op))
+				return nil
+			}
+			fmt.Print(DisassembleAt(emu, emu.PC-4))
+			zeroCounter = 0
+			continue
+		}
 
 		fmt.Print(DisassembleOpcode(op))
 
@@ -22,6 +42,17 @@ func Disassemble(gameFilePath string) {
 			zeroCounter = 0
 		}
 	}
+	return nil
+}
+
+// Disassembles the instruction at pc in emu's memory. Unlike DisassembleOpcode
+// this prints XO-CHIP's F000 NNNN with the address from the word after it
+func DisassembleAt(emu *emulator.Emulator, pc chip8.WORD) string {
+	op := emu.GetOpcode(pc)
+	if op == 0xF000 {
+		return fmt.Sprintf("0x%04X  MOV I, 0x%04X\n", op, emu.GetOpcode(pc+2))
+	}
+	return DisassembleOpcode(op)
 }
 
 func DisassembleOpcode(op chip8.WORD) string {
@@ -33,8 +64,24 @@ func DisassembleOpcode(op chip8.WORD) string {
 			str += "CLS\n"
 		case 0x00EE:
 			str += "RET\n"
+		case 0x00FB:
+			str += "SCR\n"
+		case 0x00FC:
+			str += "SCL\n"
+		case 0x00FD:
+			str += "EXIT\n"
+		case 0x00FE:
+			str += "LOW\n"
+		case 0x00FF:
+			str += "HIGH\n"
 		default:
-			str += fmt.Sprintf("SYSCALL 0x%04X\n", op&0x0FFF)
+			if op&0xFFF0 == 0x00C0 {
+				str += fmt.Sprintf("SCD %d\n", op&0x000F)
+			} else if op&0xFFF0 == 0x00D0 {
+				str += fmt.Sprintf("SCU %d\n", op&0x000F)
+			} else {
+				str += fmt.Sprintf("SYSCALL 0x%04X\n", op&0x0FFF)
+			}
 		}
 	case 0x1000:
 		str += fmt.Sprintf("JMP 0x%04X\n", op&0x0FFF)
@@ -50,7 +97,16 @@ func DisassembleOpcode(op chip8.WORD) string {
 		str += fmt.Sprintf("SNEQ reg[0x%X], %d\n", regx, nn)
 	case 0x5000:
 		regx, regy := chip8.GetXYReg(op)
-		str += fmt.Sprintf("SEQ reg[0x%X], reg[0x%X]\n", regx, regy)
+		switch op & 0x000F {
+		case 0x0:
+			str += fmt.Sprintf("SEQ reg[0x%X], reg[0x%X]\n", regx, regy)
+		case 0x2:
+			str += fmt.Sprintf("SAVE reg[0x%X], reg[0x%X]\n", regx, regy)
+		case 0x3:
+			str += fmt.Sprintf("LOAD reg[0x%X], reg[0x%X]\n", regx, regy)
+		default:
+			str += "Something went wrong!\n"
+		}
 	case 0x6000:
 		regx, _ := chip8.GetXYReg(op)
 		nn := op & 0x00FF
@@ -118,6 +174,13 @@ func DisassembleOpcode(op chip8.WORD) string {
 		}
 	case 0xF000:
 		switch op & 0x00FF {
+		case 0x00:
+			str += "MOV I, LONG\n"
+		case 0x01:
+			regx, _ := chip8.GetXYReg(op)
+			str += fmt.Sprintf("PLANE %d\n", regx)
+		case 0x02:
+			str += "AUDIO\n"
 		case 0x07:
 			regx, _ := chip8.GetXYReg(op)
 			str += fmt.Sprintf("MOV reg[0x%X], DELAY\n", regx)
@@ -136,15 +199,27 @@ func DisassembleOpcode(op chip8.WORD) string {
 		case 0x29:
 			regx, _ := chip8.GetXYReg(op)
 			str += fmt.Sprintf("FX29 reg[0x%X]\n", regx)
+		case 0x30:
+			regx, _ := chip8.GetXYReg(op)
+			str += fmt.Sprintf("FX30 reg[0x%X]\n", regx)
 		case 0x33:
 			regx, _ := chip8.GetXYReg(op)
 			str += fmt.Sprintf("FX33 reg[0x%X]\n", regx)
+		case 0x3A:
+			regx, _ := chip8.GetXYReg(op)
+			str += fmt.Sprintf("PITCH reg[0x%X]\n", regx)
 		case 0x55:
 			regx, _ := chip8.GetXYReg(op)
 			str += fmt.Sprintf("FX55 reg[0x%X]\n", regx)
 		case 0x65:
 			regx, _ := chip8.GetXYReg(op)
 			str += fmt.Sprintf("FX65 reg[0x%X]\n", regx)
+		case 0x75:
+			regx, _ := chip8.GetXYReg(op)
+			str += fmt.Sprintf("FX75 reg[0x%X]\n", regx)
+		case 0x85:
+			regx, _ := chip8.GetXYReg(op)
+			str += fmt.Sprintf("FX85 reg[0x%X]\n", regx)
 		default:
 			str += "Something went wrong!\n"
 		}
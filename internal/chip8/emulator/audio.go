@@ -0,0 +1,24 @@
+package emulator
+
+// Audio is told when the buzzer turns on and off. The buzzer sounds while the
+// sound timer is nonzero, so it turns on when FX18 sets the timer and off when
+// the timer runs out at the end of a frame.
+type Audio interface {
+	Buzzer(on bool)
+
+	// Called at the end of every 60Hz frame, after the timers tick, for
+	// implementations that generate samples frame by frame
+	EndFrame()
+}
+
+// Tells the audio about a change of the buzzer after the sound timer is set or ticks
+func (h *Emulator) updateBuzzer() {
+	on := h.SoundDelay > 0
+	if on == h.buzzing {
+		return
+	}
+	h.buzzing = on
+	if h.Audio != nil {
+		h.Audio.Buzzer(on)
+	}
+}
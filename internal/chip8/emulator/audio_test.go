@@ -0,0 +1,41 @@
+package emulator
+
+import (
+	"reflect"
+	"testing"
+)
+
+type recordedAudio struct {
+	events []string
+}
+
+func (a *recordedAudio) Buzzer(on bool) {
+	if on {
+		a.events = append(a.events, "on")
+	} else {
+		a.events = append(a.events, "off")
+	}
+}
+
+func (a *recordedAudio) EndFrame() {
+	a.events = append(a.events, "frame")
+}
+
+func TestBuzzerTransitions(t *testing.T) {
+	audio := &recordedAudio{}
+	// MOV V0 2, MOV ST V0, JMP 0x204
+	h, err := NewEmulatorFromBytes([]byte{0x60, 0x02, 0xF0, 0x18, 0x12, 0x04}, WithAudio(audio), WithInstructionsPerFrame(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := h.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"on", "frame", "off", "frame", "frame", "frame"}
+	if !reflect.DeepEqual(audio.events, want) {
+		t.Errorf("got %v, want %v", audio.events, want)
+	}
+}
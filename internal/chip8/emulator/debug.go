@@ -0,0 +1,342 @@
+package emulator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+)
+
+// ErrBreak is wrapped by the *Break that Step returns when a breakpoint or watchpoint
+// triggers, or a StepOver, StepOut or RunTo completes
+var ErrBreak = errors.New("break")
+
+// Break stops execution for the debugger. It is returned as an error from Step
+// so it stops RunFrame and anything else driving the emulator.
+type Break struct {
+	PC     chip8.WORD // Address execution stopped at
+	Reason string
+}
+
+func (b *Break) Error() string {
+	return fmt.Sprintf("break at 0x%04X: %s", b.PC, b.Reason)
+}
+
+func (b *Break) Unwrap() error {
+	return ErrBreak
+}
+
+// Breakpoint stops execution before the instruction at Addr runs. With a
+// Condition it only stops while the condition holds, and with AnyAddr it
+// stops at whichever instruction the condition holds at.
+type Breakpoint struct {
+	Addr      chip8.WORD
+	AnyAddr   bool
+	Condition *Condition
+}
+
+func (b Breakpoint) String() string {
+	str := fmt.Sprintf("0x%04X", b.Addr)
+	if b.AnyAddr {
+		str = "*"
+	}
+	if b.Condition != nil {
+		str += " if " + b.Condition.String()
+	}
+	return str
+}
+
+type WatchKind int
+
+const (
+	WatchRead WatchKind = 1 << iota
+	WatchWrite
+	WatchRegister
+)
+
+// Watchpoint stops execution after an instruction reads or writes the memory at
+// Addr, or changes the register Register (0-F for V0-VF, 16 for I)
+type Watchpoint struct {
+	Kind     WatchKind
+	Addr     int
+	Register int
+}
+
+func (w Watchpoint) String() string {
+	if w.Kind == WatchRegister {
+		return "change " + registerName(w.Register)
+	}
+	access := ""
+	if w.Kind&WatchRead != 0 {
+		access += "r"
+	}
+	if w.Kind&WatchWrite != 0 {
+		access += "w"
+	}
+	return fmt.Sprintf("%s 0x%04X", access, w.Addr)
+}
+
+// Index used for I in Watchpoint.Register and register operands
+const RegisterI = 16
+
+func registerName(reg int) string {
+	if reg == RegisterI {
+		return "I"
+	}
+	return fmt.Sprintf("V%X", reg)
+}
+
+// Parses V0-VF or I
+func ParseRegister(s string) (int, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "I" {
+		return RegisterI, true
+	}
+	if len(s) == 2 && s[0] == 'V' {
+		reg, err := strconv.ParseUint(s[1:], 16, 8)
+		return int(reg), err == nil
+	}
+	return 0, false
+}
+
+type operandKind int
+
+const (
+	operandRegister operandKind = iota
+	operandPC
+	operandDelay
+	operandSound
+	operandMemory
+	operandValue
+)
+
+type operand struct {
+	kind  operandKind
+	index int // Register number, memory address or literal value
+}
+
+func parseOperand(s string) (operand, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if reg, ok := ParseRegister(s); ok {
+		return operand{kind: operandRegister, index: reg}, nil
+	}
+	switch s {
+	case "PC":
+		return operand{kind: operandPC}, nil
+	case "DT", "DELAY":
+		return operand{kind: operandDelay}, nil
+	case "ST", "SND_DELAY":
+		return operand{kind: operandSound}, nil
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		addr, err := strconv.ParseUint(strings.ToLower(s[1:len(s)-1]), 0, 16)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid address %q", s)
+		}
+		return operand{kind: operandMemory, index: int(addr)}, nil
+	}
+	value, err := strconv.ParseUint(strings.ToLower(s), 0, 16)
+	if err != nil {
+		return operand{}, fmt.Errorf("invalid operand %q", s)
+	}
+	return operand{kind: operandValue, index: int(value)}, nil
+}
+
+func (o operand) eval(h *Emulator) int {
+	switch o.kind {
+	case operandRegister:
+		if o.index == RegisterI {
+			return int(h.I)
+		}
+		return int(h.Registers[o.index])
+	case operandPC:
+		return int(h.PC)
+	case operandDelay:
+		return int(h.Delay)
+	case operandSound:
+		return int(h.SoundDelay)
+	case operandMemory:
+		if o.index < len(h.Memory) {
+			return int(h.Memory[o.index])
+		}
+		return 0
+	}
+	return o.index
+}
+
+func (o operand) String() string {
+	switch o.kind {
+	case operandRegister:
+		return registerName(o.index)
+	case operandPC:
+		return "PC"
+	case operandDelay:
+		return "DT"
+	case operandSound:
+		return "ST"
+	case operandMemory:
+		return fmt.Sprintf("[0x%04X]", o.index)
+	}
+	return fmt.Sprintf("0x%X", o.index)
+}
+
+// Condition compares two operands, each a register (V0-VF, I), PC, a timer
+// (DT, ST), a memory byte ([0x300]) or a number
+type Condition struct {
+	left, right operand
+	op          string
+}
+
+var comparisons = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// Parses a condition such as "V3 == 0x10" or "[0x300] != V0"
+func ParseCondition(s string) (*Condition, error) {
+	for _, op := range comparisons {
+		left, right, found := strings.Cut(s, op)
+		if !found {
+			continue
+		}
+		l, err := parseOperand(left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := parseOperand(right)
+		if err != nil {
+			return nil, err
+		}
+		return &Condition{left: l, right: r, op: op}, nil
+	}
+	return nil, fmt.Errorf("no comparison in condition %q", s)
+}
+
+// Reports whether the condition holds for the emulator's current state
+func (c Condition) Eval(h *Emulator) bool {
+	l, r := c.left.eval(h), c.right.eval(h)
+	switch c.op {
+	case "==":
+		return l == r
+	case "!=":
+		return l != r
+	case "<=":
+		return l <= r
+	case ">=":
+		return l >= r
+	case "<":
+		return l < r
+	}
+	return l > r
+}
+
+func (c Condition) String() string {
+	return fmt.Sprintf("%s %s %s", c.left, c.op, c.right)
+}
+
+// Checks the breakpoints before the instruction at PC runs. The breakpoint that
+// last stopped execution is skipped once so execution can be resumed past it
+func (h *Emulator) checkBreakpoints() error {
+	skip := h.skipBreakAt == int(h.PC)
+	h.skipBreakAt = -1
+	if skip {
+		return nil
+	}
+
+	for _, bp := range h.Breakpoints {
+		if !bp.AnyAddr && bp.Addr != h.PC {
+			continue
+		}
+		if bp.Condition != nil && !bp.Condition.Eval(h) {
+			continue
+		}
+		h.skipBreakAt = int(h.PC)
+		return &Break{PC: h.PC, Reason: "breakpoint " + bp.String()}
+	}
+	return nil
+}
+
+// Records a hit on any memory watchpoint covering addr, reported once the instruction completes
+func (h *Emulator) checkMemoryWatch(addr int, kind WatchKind) {
+	if h.watchHit != "" {
+		return
+	}
+	for _, wp := range h.Watchpoints {
+		if wp.Kind&kind != 0 && wp.Addr == addr {
+			access := "read"
+			if kind == WatchWrite {
+				access = "write"
+			}
+			h.watchHit = fmt.Sprintf("watchpoint %s: %s of 0x%04X", wp, access, addr)
+			return
+		}
+	}
+}
+
+// Records a hit on any register watchpoint whose register differs from before
+func (h *Emulator) checkRegisterWatch(registers [16]chip8.BYTE, i chip8.WORD) {
+	if h.watchHit != "" {
+		return
+	}
+	for _, wp := range h.Watchpoints {
+		if wp.Kind != WatchRegister {
+			continue
+		}
+		if wp.Register == RegisterI && i != h.I {
+			h.watchHit = fmt.Sprintf("watchpoint %s: 0x%X -> 0x%X", wp, i, h.I)
+			return
+		}
+		if wp.Register < len(registers) && registers[wp.Register] != h.Registers[wp.Register] {
+			h.watchHit = fmt.Sprintf("watchpoint %s: 0x%X -> 0x%X", wp, registers[wp.Register], h.Registers[wp.Register])
+			return
+		}
+	}
+}
+
+// Temporary stop set by StepOver, StepOut and RunTo
+type runTarget struct {
+	reason string
+	depth  int // Stop once the stack is at most this deep, -1 to ignore
+	addr   int // Stop once PC reaches this address, -1 to ignore
+}
+
+func (t runTarget) reached(h *Emulator) bool {
+	if t.depth >= 0 && len(h.Stack) <= t.depth {
+		return true
+	}
+	return t.addr >= 0 && int(h.PC) == t.addr
+}
+
+// Stops execution with a *Break once the instruction at PC has completed. A
+// subroutine call (2NNN) is treated as one instruction by running until it returns.
+func (h *Emulator) StepOver() {
+	h.target = &runTarget{reason: "step over", depth: len(h.Stack), addr: -1}
+}
+
+// Stops execution with a *Break once the current subroutine has returned
+func (h *Emulator) StepOut() error {
+	if len(h.Stack) == 0 {
+		return errors.New("not in a subroutine")
+	}
+	h.target = &runTarget{reason: "step out", depth: len(h.Stack) - 1, addr: -1}
+	return nil
+}
+
+// Stops execution with a *Break once PC reaches addr
+func (h *Emulator) RunTo(addr chip8.WORD) {
+	h.target = &runTarget{reason: fmt.Sprintf("run to 0x%04X", addr), depth: -1, addr: int(addr)}
+}
+
+// Drops the stop set by StepOver, StepOut or RunTo
+func (h *Emulator) CancelRun() {
+	h.target = nil
+}
+
+// Checks the stop set by StepOver, StepOut or RunTo after an instruction completes
+func (h *Emulator) checkTarget() error {
+	if h.target == nil || !h.target.reached(h) {
+		return nil
+	}
+	reason := h.target.reason
+	h.target = nil
+	return &Break{PC: h.PC, Reason: reason}
+}
@@ -0,0 +1,233 @@
+package emulator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+)
+
+// Steps until a *Break is returned, failing on any other error or after max instructions
+func runToBreak(t *testing.T, h *Emulator, max int) *Break {
+	t.Helper()
+	for i := 0; i < max; i++ {
+		err := h.Step()
+		if err == nil {
+			continue
+		}
+		var brk *Break
+		if !errors.As(err, &brk) {
+			t.Fatal(err)
+		}
+		return brk
+	}
+	t.Fatalf("no break after %d instructions", max)
+	return nil
+}
+
+func TestParseCondition(t *testing.T) {
+	h := newTestEmulator(t, Quirks{}, 0x1200)
+	h.Registers[3] = 0x10
+	h.I = 0x300
+	h.Delay = 5
+	h.Memory[0x300] = 0x42
+
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{"V3 == 0x10", true},
+		{"v3 != 16", false},
+		{"I >= 0x300", true},
+		{"PC < 0x200", false},
+		{"DT > 4", true},
+		{"ST <= 0", true},
+		{"[0x300] == 0x42", true},
+		{"[0x300] == V3", false},
+	}
+	for _, tt := range tests {
+		c, err := ParseCondition(tt.cond)
+		if err != nil {
+			t.Errorf("%q: %v", tt.cond, err)
+			continue
+		}
+		if got := c.Eval(h); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"V3", "VG == 1", "V3 == [zz]", "V3 == 0x10000", "== 1"} {
+		if _, err := ParseCondition(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestParseOperand(t *testing.T) {
+	tests := []struct {
+		in   string
+		want operand
+	}{
+		{"VA", operand{kind: operandRegister, index: 0xA}},
+		{"i", operand{kind: operandRegister, index: RegisterI}},
+		{"pc", operand{kind: operandPC}},
+		{"DELAY", operand{kind: operandDelay}},
+		{"st", operand{kind: operandSound}},
+		{"[0x2A4]", operand{kind: operandMemory, index: 0x2A4}},
+		{"0x1F", operand{kind: operandValue, index: 0x1F}},
+		{"31", operand{kind: operandValue, index: 31}},
+	}
+	for _, tt := range tests {
+		got, err := parseOperand(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	// 0x200: V0 += 1, 0x202: jump back to 0x200
+	program := []chip8.WORD{0x7001, 0x1200}
+
+	t.Run("address", func(t *testing.T) {
+		h := newTestEmulator(t, Quirks{}, program...)
+		h.Breakpoints = []Breakpoint{{Addr: 0x202}}
+		brk := runToBreak(t, h, 10)
+		if brk.PC != 0x202 || h.Registers[0] != 1 {
+			t.Errorf("stopped at 0x%04X with V0=%d, want 0x0202 with V0=1", brk.PC, h.Registers[0])
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		// The breakpoint that stopped execution is skipped once, then hit on the next pass
+		h := newTestEmulator(t, Quirks{}, program...)
+		h.Breakpoints = []Breakpoint{{Addr: 0x202}}
+		runToBreak(t, h, 10)
+		brk := runToBreak(t, h, 10)
+		if brk.PC != 0x202 || h.Registers[0] != 2 {
+			t.Errorf("stopped at 0x%04X with V0=%d, want 0x0202 with V0=2", brk.PC, h.Registers[0])
+		}
+	})
+
+	t.Run("condition", func(t *testing.T) {
+		h := newTestEmulator(t, Quirks{}, program...)
+		cond, err := ParseCondition("V0 == 3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Breakpoints = []Breakpoint{{Addr: 0x200, Condition: cond}}
+		brk := runToBreak(t, h, 20)
+		if brk.PC != 0x200 || h.Registers[0] != 3 {
+			t.Errorf("stopped at 0x%04X with V0=%d, want 0x0200 with V0=3", brk.PC, h.Registers[0])
+		}
+	})
+
+	t.Run("any address", func(t *testing.T) {
+		h := newTestEmulator(t, Quirks{}, program...)
+		cond, err := ParseCondition("V0 == 2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Breakpoints = []Breakpoint{{AnyAddr: true, Condition: cond}}
+		brk := runToBreak(t, h, 20)
+		// V0 becomes 2 at 0x200, so the next instruction is the jump
+		if brk.PC != 0x202 || h.Registers[0] != 2 {
+			t.Errorf("stopped at 0x%04X with V0=%d, want 0x0202 with V0=2", brk.PC, h.Registers[0])
+		}
+	})
+}
+
+func TestWatchpoints(t *testing.T) {
+	// 0x200: I = 0x300, 0x202: V0 = 7, 0x204: store V0 at I, 0x206: load V0 from I, 0x208: loop
+	program := []chip8.WORD{0xA300, 0x6007, 0xF055, 0xF065, 0x1208}
+
+	tests := []struct {
+		name   string
+		watch  Watchpoint
+		stopPC chip8.WORD // PC after the instruction that triggered it
+	}{
+		{"write", Watchpoint{Kind: WatchWrite, Addr: 0x300}, 0x206},
+		{"read", Watchpoint{Kind: WatchRead, Addr: 0x300}, 0x208},
+		{"read or write", Watchpoint{Kind: WatchRead | WatchWrite, Addr: 0x300}, 0x206},
+		{"register", Watchpoint{Kind: WatchRegister, Register: 0}, 0x204},
+		{"register I", Watchpoint{Kind: WatchRegister, Register: RegisterI}, 0x202},
+	}
+	for _, tt := range tests {
+		h := newTestEmulator(t, Quirks{}, program...)
+		h.Watchpoints = []Watchpoint{tt.watch}
+		brk := runToBreak(t, h, 10)
+		if brk.PC != tt.stopPC {
+			t.Errorf("%s: stopped at 0x%04X, want 0x%04X (%s)", tt.name, brk.PC, tt.stopPC, brk.Reason)
+		}
+	}
+
+	// Other addresses don't trigger
+	h := newTestEmulator(t, Quirks{}, program...)
+	h.Watchpoints = []Watchpoint{{Kind: WatchRead | WatchWrite, Addr: 0x301}}
+	step(t, h, 10)
+}
+
+func TestRunTargets(t *testing.T) {
+	program := []chip8.WORD{
+		0x2206, // 0x200: call 0x206
+		0x7001, // 0x202: V0 += 1
+		0x1204, // 0x204: loop
+		0x6105, // 0x206: V1 = 5
+		0x220C, // 0x208: call 0x20C
+		0x00EE, // 0x20A: return
+		0x6207, // 0x20C: V2 = 7
+		0x00EE, // 0x20E: return
+	}
+
+	t.Run("step over call", func(t *testing.T) {
+		h := newTestEmulator(t, Quirks{}, program...)
+		h.StepOver()
+		brk := runToBreak(t, h, 20)
+		if brk.PC != 0x202 || len(h.Stack) != 0 || h.Registers[1] != 5 || h.Registers[2] != 7 {
+			t.Errorf("stopped at 0x%04X with depth %d, V1=%d, V2=%d", brk.PC, len(h.Stack), h.Registers[1], h.Registers[2])
+		}
+	})
+
+	t.Run("step over instruction", func(t *testing.T) {
+		h := newTestEmulator(t, Quirks{}, program...)
+		step(t, h, 1)
+		h.StepOver()
+		brk := runToBreak(t, h, 1)
+		if brk.PC != 0x208 {
+			t.Errorf("stopped at 0x%04X, want 0x0208", brk.PC)
+		}
+	})
+
+	t.Run("step out", func(t *testing.T) {
+		h := newTestEmulator(t, Quirks{}, program...)
+		step(t, h, 3)
+		if h.PC != 0x20C || len(h.Stack) != 2 {
+			t.Fatalf("at 0x%04X with depth %d, want 0x020C with depth 2", h.PC, len(h.Stack))
+		}
+		if err := h.StepOut(); err != nil {
+			t.Fatal(err)
+		}
+		brk := runToBreak(t, h, 20)
+		if brk.PC != 0x20A || len(h.Stack) != 1 {
+			t.Errorf("stopped at 0x%04X with depth %d, want 0x020A with depth 1", brk.PC, len(h.Stack))
+		}
+	})
+
+	t.Run("step out of main", func(t *testing.T) {
+		h := newTestEmulator(t, Quirks{}, program...)
+		if err := h.StepOut(); err == nil {
+			t.Error("expected an error outside a subroutine")
+		}
+	})
+
+	t.Run("run to", func(t *testing.T) {
+		h := newTestEmulator(t, Quirks{}, program...)
+		h.RunTo(0x204)
+		brk := runToBreak(t, h, 20)
+		if brk.PC != 0x204 || h.Registers[0] != 1 {
+			t.Errorf("stopped at 0x%04X with V0=%d, want 0x0204 with V0=1", brk.PC, h.Registers[0])
+		}
+		// The stop is dropped once reached
+		step(t, h, 10)
+	})
+}
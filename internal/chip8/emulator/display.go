@@ -0,0 +1,169 @@
+package emulator
+
+import (
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+)
+
+// Display is the screen the emulator draws to. Each pixel is a bitmask of the
+// XO-CHIP planes it is lit in, so it is 0 or 1 outside of XO-CHIP.
+//
+// FrameBuffer is used unless WithDisplay gives another. Frontends wanting to be
+// told about each frame can embed *FrameBuffer and override FrameReady.
+type Display interface {
+	// Resizes the display, clearing it
+	SetResolution(width, height int)
+	Resolution() (width, height int)
+
+	// Unlights the pixels in the given planes
+	Clear(planes chip8.BYTE)
+
+	// XORs a sprite into plane with its top left corner at (x, y), which are on
+	// screen. Each row is left aligned in 16 bits and width pixels wide. Pixels
+	// past the edges wrap around unless clip is set. Reports whether any lit
+	// pixel was unlit.
+	DrawSprite(x, y int, rows []chip8.WORD, width int, plane chip8.BYTE, clip bool) bool
+
+	// Moves the pixels of the given planes by dx, dy. Pixels scrolled in from offscreen are unlit
+	Scroll(dx, dy int, planes chip8.BYTE)
+
+	Pixel(x, y int) chip8.BYTE
+	// Sets a pixel directly, used when restoring save states
+	SetPixel(x, y int, pixel chip8.BYTE)
+
+	// Reports whether any pixel has changed since the last ClearDirty
+	Dirty() bool
+	ClearDirty()
+
+	// Called at the end of every 60Hz frame
+	FrameReady()
+}
+
+// CollisionOverlay is implemented by displays that remember which pixels the
+// most recent sprite draw unlit, for highlighting collisions while debugging.
+// It isn't part of the machine state and isn't saved.
+type CollisionOverlay interface {
+	Collided(x, y int) bool
+}
+
+// FrameBuffer is the default in-memory Display
+type FrameBuffer struct {
+	// Called by FrameReady, when set
+	OnFrame func()
+
+	pixels   [][]chip8.BYTE // Indexed as pixels[x][y]
+	collided [][]bool
+	dirty    bool
+}
+
+func NewFrameBuffer() *FrameBuffer {
+	fb := &FrameBuffer{}
+	fb.SetResolution(loResWidth, loResHeight)
+	return fb
+}
+
+func (fb *FrameBuffer) SetResolution(width, height int) {
+	fb.pixels = make([][]chip8.BYTE, width)
+	fb.collided = make([][]bool, width)
+	for x := range fb.pixels {
+		fb.pixels[x] = make([]chip8.BYTE, height)
+		fb.collided[x] = make([]bool, height)
+	}
+	fb.dirty = true
+}
+
+func (fb *FrameBuffer) Resolution() (int, int) {
+	return len(fb.pixels), len(fb.pixels[0])
+}
+
+func (fb *FrameBuffer) Clear(planes chip8.BYTE) {
+	for x := range fb.pixels {
+		for y := range fb.pixels[x] {
+			fb.pixels[x][y] &^= planes
+		}
+	}
+	fb.clearCollisions()
+	fb.dirty = true
+}
+
+func (fb *FrameBuffer) DrawSprite(x, y int, rows []chip8.WORD, width int, plane chip8.BYTE, clip bool) bool {
+	fb.clearCollisions()
+	screenWidth, screenHeight := fb.Resolution()
+
+	collision := false
+	for yline, data := range rows {
+		for xpixel := 0; xpixel < width; xpixel++ {
+			if data&(chip8.WORD(0x8000)>>xpixel) == 0 {
+				continue
+			}
+			px, py := x+xpixel, y+yline
+			if clip && (px >= screenWidth || py >= screenHeight) {
+				continue
+			}
+			px %= screenWidth
+			py %= screenHeight
+
+			fb.pixels[px][py] ^= plane
+			if fb.pixels[px][py]&plane == 0 {
+				collision = true
+				fb.collided[px][py] = fb.pixels[px][py] == 0
+			}
+		}
+	}
+	fb.dirty = true
+	return collision
+}
+
+func (fb *FrameBuffer) Scroll(dx, dy int, planes chip8.BYTE) {
+	width, height := fb.Resolution()
+	scrolled := make([][]chip8.BYTE, width)
+	for x := range scrolled {
+		scrolled[x] = make([]chip8.BYTE, height)
+		for y := range scrolled[x] {
+			pixel := fb.pixels[x][y] &^ planes
+			srcx, srcy := x-dx, y-dy
+			if srcx >= 0 && srcx < width && srcy >= 0 && srcy < height {
+				pixel |= fb.pixels[srcx][srcy] & planes
+			}
+			scrolled[x][y] = pixel
+		}
+	}
+	fb.pixels = scrolled
+	fb.clearCollisions()
+	fb.dirty = true
+}
+
+func (fb *FrameBuffer) Pixel(x, y int) chip8.BYTE {
+	return fb.pixels[x][y]
+}
+
+func (fb *FrameBuffer) SetPixel(x, y int, pixel chip8.BYTE) {
+	fb.pixels[x][y] = pixel
+	fb.collided[x][y] = false
+	fb.dirty = true
+}
+
+func (fb *FrameBuffer) Dirty() bool {
+	return fb.dirty
+}
+
+func (fb *FrameBuffer) ClearDirty() {
+	fb.dirty = false
+}
+
+func (fb *FrameBuffer) FrameReady() {
+	if fb.OnFrame != nil {
+		fb.OnFrame()
+	}
+}
+
+func (fb *FrameBuffer) Collided(x, y int) bool {
+	return fb.collided[x][y]
+}
+
+func (fb *FrameBuffer) clearCollisions() {
+	for x := range fb.collided {
+		for y := range fb.collided[x] {
+			fb.collided[x][y] = false
+		}
+	}
+}
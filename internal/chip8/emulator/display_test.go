@@ -0,0 +1,82 @@
+package emulator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+)
+
+func TestFrameBufferCollisionOverlay(t *testing.T) {
+	fb := NewFrameBuffer()
+	rows := []chip8.WORD{0xC000} // Two pixels wide
+
+	if fb.DrawSprite(0, 0, rows, 8, 1, false) {
+		t.Error("first draw reported a collision")
+	}
+	if fb.Pixel(0, 0) != 1 || fb.Pixel(1, 0) != 1 {
+		t.Fatal("sprite pixels not lit")
+	}
+
+	fb.ClearDirty()
+	if !fb.DrawSprite(1, 0, rows, 8, 1, false) {
+		t.Error("overlapping draw did not report a collision")
+	}
+	if !fb.Dirty() {
+		t.Error("display not dirty after a draw")
+	}
+	if fb.Pixel(1, 0) != 0 || !fb.Collided(1, 0) {
+		t.Error("pixel 1 should be unlit and marked as collided")
+	}
+	if fb.Collided(0, 0) || fb.Collided(2, 0) {
+		t.Error("only unlit pixels should be marked as collided")
+	}
+
+	// The overlay only covers the most recent draw
+	fb.DrawSprite(10, 10, rows, 8, 1, false)
+	if fb.Collided(1, 0) {
+		t.Error("collision overlay not cleared by the next draw")
+	}
+}
+
+// Counts frames, as a frontend embedding FrameBuffer would
+type countingDisplay struct {
+	*FrameBuffer
+	frames int
+}
+
+func (d *countingDisplay) FrameReady() {
+	d.frames++
+}
+
+func TestCustomDisplay(t *testing.T) {
+	display := &countingDisplay{FrameBuffer: NewFrameBuffer()}
+	h, err := NewEmulatorFromBytes([]byte{0xD0, 0x15, 0x12, 0x02}, WithDisplay(display), WithQuirks(Quirks{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := h.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if display.frames != 3 {
+		t.Errorf("FrameReady called %d times, want 3", display.frames)
+	}
+	if display.Pixel(0, 0) != 1 {
+		t.Error("DXYN did not draw to the custom display")
+	}
+
+	// Save states read and restore the screen through the display
+	var state bytes.Buffer
+	if err := h.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+	display.Clear(1)
+	if err := h.LoadState(&state); err != nil {
+		t.Fatal(err)
+	}
+	if display.Pixel(0, 0) != 1 {
+		t.Error("LoadState did not restore the screen")
+	}
+}
@@ -2,41 +2,215 @@ package emulator
 
 import (
 	"fmt"
-	"math/rand"
+	"io"
+	"math"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/kctjohnson/chip8-emu/internal/chip8"
 )
 
-var timerCap chip8.BYTE = 60
+// Instructions executed per 60Hz frame unless WithInstructionsPerFrame is given
+const DefaultInstructionsPerFrame = 10
+
+const (
+	loResWidth  = 64
+	loResHeight = 32
+	hiResWidth  = 128
+	hiResHeight = 64
+)
 
 type Emulator struct {
-	Memory        [0xFFF]chip8.BYTE
+	Memory        []chip8.BYTE
 	Registers     [16]chip8.BYTE
 	I             chip8.WORD
 	PC            chip8.WORD
 	Stack         []chip8.WORD
-	Inputs        [16]chip8.BYTE
 	Delay         chip8.BYTE
 	SoundDelay    chip8.BYTE
-	LastTick      time.Time
 	CurrentOpcode chip8.WORD
+	RPLFlags      [16]chip8.BYTE // Persist across resets like the HP-48 flag registers
+	HiRes         bool
+	Exited        bool
+	WaitingForKey bool   // FX0A is halting execution until a key is pressed and released
+	Cycles        uint64 // Instructions executed since the last reset
+	Frame         uint64 // 60Hz frames elapsed since the last reset
+
+	// XO-CHIP state
+	Planes       chip8.BYTE // Bitmask of the bitplanes drawn to and cleared
+	AudioPattern [16]chip8.BYTE
+	Pitch        chip8.BYTE
+
+	ROM         []byte // Program image loaded at 0x200 on every reset
+	Platform    Platform
+	Quirks      Quirks
+	FontAddress chip8.WORD // Both fonts must fit below 0x200
+	Seed        int64      // Seeds the random source for CXNN on every reset
+
+	// Number of instructions run between each tick of the timers
+	InstructionsPerFrame int
+
+	// Checked by Step, which returns a *Break when one triggers
+	Breakpoints []Breakpoint
+	Watchpoints []Watchpoint
+
+	// Called with the address and opcode of every instruction just before it executes
+	OnExecute func(pc, op chip8.WORD)
+
+	// The screen, a FrameBuffer unless WithDisplay gives another
+	Display Display
+
+	// The keypad, a Keypad unless WithInput gives another
+	Input InputSource
+
+	// Told when the buzzer turns on and off, silent when nil
+	Audio Audio
+
+	customQuirks bool
+	newSource    func(seed int64) RandSource
+	rng          RandSource
+
+	// Key pressed while waiting in FX0A, -1 until one is pressed
+	waitKey int
+	// Whether Audio was last told the buzzer is on
+	buzzing bool
+
+	// Set by DXYN when the display wait quirk is enabled, cleared on the next timer tick
+	waitVBlank bool
+	// Instructions executed in the current frame
+	frameCycle int
+
+	// Address of the breakpoint that last stopped execution, -1 if none
+	skipBreakAt int
+	// Reason of the first watchpoint hit by the instruction being executed
+	watchHit string
+	// Stop requested by StepOver, StepOut or RunTo
+	target *runTarget
+}
+
+// Option configures an Emulator at construction time
+type Option func(*Emulator)
+
+// Sets the quirk profile used to interpret ambiguous opcodes
+func WithQuirks(quirks Quirks) Option {
+	return func(h *Emulator) {
+		h.Quirks = quirks
+		h.customQuirks = true
+	}
+}
+
+// Sets where the fonts are installed, for ROMs that expect them at a fixed address such as 0x050
+func WithFontAddress(addr chip8.WORD) Option {
+	return func(h *Emulator) {
+		h.FontAddress = addr
+	}
+}
+
+// Sets the seed for the CXNN random numbers, making runs reproducible
+func WithSeed(seed int64) Option {
+	return func(h *Emulator) {
+		h.Seed = seed
+	}
+}
+
+// Replaces the random source used for CXNN. newSource is called with the seed on every reset
+func WithRandSource(newSource func(seed int64) RandSource) Option {
+	return func(h *Emulator) {
+		h.newSource = newSource
+	}
+}
+
+// Sets how many instructions are executed per 60Hz frame, which sets the emulation speed
+func WithInstructionsPerFrame(n int) Option {
+	return func(h *Emulator) {
+		h.InstructionsPerFrame = n
+	}
+}
+
+// Draws to display instead of a new FrameBuffer
+func WithDisplay(display Display) Option {
+	return func(h *Emulator) {
+		h.Display = display
+	}
+}
 
-	FilePath string
+// Reads the keypad from input instead of a new Keypad
+func WithInput(input InputSource) Option {
+	return func(h *Emulator) {
+		h.Input = input
+	}
+}
+
+// Reports the buzzer to audio
+func WithAudio(audio Audio) Option {
+	return func(h *Emulator) {
+		h.Audio = audio
+	}
+}
+
+// Sets the platform being emulated. Unless WithQuirks is also given the
+// platform's default quirk profile is used.
+func WithPlatform(platform Platform) Option {
+	return func(h *Emulator) {
+		h.Platform = platform
+	}
+}
+
+// Creates an emulator running the ROM file at gameFilePath
+func NewEmulator(gameFilePath string, opts ...Option) (*Emulator, error) {
+	rom, err := os.ReadFile(gameFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return NewEmulatorFromBytes(rom, opts...)
+}
 
-	ScreenData [64][32]chip8.BYTE
+// Creates an emulator running the ROM read from r
+func NewEmulatorFromReader(r io.Reader, opts ...Option) (*Emulator, error) {
+	rom, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewEmulatorFromBytes(rom, opts...)
 }
 
-func NewEmulator(gameFilePath string) *Emulator {
+// Creates an emulator running a copy of rom
+func NewEmulatorFromBytes(rom []byte, opts ...Option) (*Emulator, error) {
 	emu := &Emulator{
-		FilePath: gameFilePath,
+		ROM:         append([]byte(nil), rom...),
+		Platform:    PlatformChip8,
+		FontAddress: DefaultFontAddress,
+		Seed:        time.Now().UnixNano(),
+		newSource:   NewRandSource,
+
+		InstructionsPerFrame: DefaultInstructionsPerFrame,
+	}
+	for _, opt := range opts {
+		opt(emu)
+	}
+	if emu.Display == nil {
+		emu.Display = NewFrameBuffer()
+	}
+	if emu.Input == nil {
+		emu.Input = NewKeypad()
+	}
+	if !emu.customQuirks {
+		emu.Quirks = emu.Platform.DefaultQuirks()
 	}
-	emu.CPUReset()
-	return emu
+	if emu.InstructionsPerFrame < 1 {
+		return nil, fmt.Errorf("instructions per frame must be at least 1, got %d", emu.InstructionsPerFrame)
+	}
+	if int(emu.FontAddress)+fontSize > 0x200 {
+		return nil, fmt.Errorf("font address 0x%03X leaves no room for the fonts below 0x200", emu.FontAddress)
+	}
+	if err := emu.CPUReset(); err != nil {
+		return nil, err
+	}
+	return emu, nil
 }
 
-func (h *Emulator) CPUReset() {
+func (h *Emulator) CPUReset() error {
 	// Reset all of the data
 	h.I = 0
 	h.PC = 0x200
@@ -45,57 +219,232 @@ func (h *Emulator) CPUReset() {
 	}
 	h.Delay = 0
 	h.SoundDelay = 0
-	h.LastTick = time.Now()
+	h.Cycles = 0
+	h.Frame = 0
+	h.frameCycle = 0
+	h.waitVBlank = false
+	h.skipBreakAt = -1
+	h.watchHit = ""
+	h.target = nil
 	h.Stack = []chip8.WORD{}
-	h.Inputs = [16]chip8.BYTE{}
-	h.ScreenData = [64][32]chip8.BYTE{}
-	h.Memory = [0xFFF]chip8.BYTE{}
+	h.rng = h.newSource(h.Seed)
+	h.Exited = false
+	h.WaitingForKey = false
+	h.waitKey = -1
+	h.Planes = 1
+	h.AudioPattern = [16]chip8.BYTE{}
+	h.Pitch = 64
+	h.updateBuzzer()
+	h.setResolution(false)
+	h.Memory = make([]chip8.BYTE, h.Platform.MemorySize())
+	h.loadFonts()
 
 	// Load in the game
-	gameFile, err := os.ReadFile(h.FilePath)
-	if err != nil {
-		panic(err)
+	if len(h.ROM) > len(h.Memory)-0x200 {
+		return fmt.Errorf("rom is %d bytes but only %d fit in memory: %w", len(h.ROM), len(h.Memory)-0x200, ErrROMTooLarge)
 	}
+	for i := range h.ROM {
+		h.Memory[i+0x200] = chip8.BYTE(h.ROM[i])
+	}
+	return nil
+}
 
-	for i := range gameFile {
-		h.Memory[i+0x200] = chip8.BYTE(gameFile[i])
+// Switches between the 64x32 and 128x64 display modes, clearing the screen
+func (h *Emulator) setResolution(hires bool) {
+	h.HiRes = hires
+	h.Display.SetResolution(resolution(hires))
+}
+
+func resolution(hires bool) (width, height int) {
+	if hires {
+		return hiResWidth, hiResHeight
+	}
+	return loResWidth, loResHeight
+}
+
+// Skips over the next instruction, which is 4 bytes long when it is an XO-CHIP F000 NNNN
+func (h *Emulator) skipNextInstruction() {
+	if h.Platform == PlatformXOChip && h.GetOpcode(h.PC) == 0xF000 {
+		h.PC += 4
+	} else {
+		h.PC += 2
+	}
+}
+
+// Width of the display in the current resolution
+func (h Emulator) ScreenWidth() int {
+	width, _ := h.Display.Resolution()
+	return width
+}
+
+// Height of the display in the current resolution
+func (h Emulator) ScreenHeight() int {
+	_, height := h.Display.Resolution()
+	return height
+}
+
+// Characters ScreenText draws for each combination of the two XO-CHIP planes
+var screenTextPlanes = [4]byte{'.', '#', '+', '@'}
+
+// Renders the display as text, one line per row. Unlit pixels are '.', and lit
+// pixels '#' for the first plane, '+' for the second and '@' for both.
+func (h Emulator) ScreenText() string {
+	var sb strings.Builder
+	for y := 0; y < h.ScreenHeight(); y++ {
+		for x := 0; x < h.ScreenWidth(); x++ {
+			sb.WriteByte(screenTextPlanes[h.Display.Pixel(x, y)&3])
+		}
+		sb.WriteByte('\n')
 	}
+	return sb.String()
 }
 
-func (h *Emulator) GetNextOpcode() chip8.WORD {
-	res := (chip8.WORD(h.Memory[h.PC]) << 8) | chip8.WORD(h.Memory[h.PC+1])
+func (h *Emulator) GetNextOpcode() (chip8.WORD, error) {
+	if int(h.PC)+1 >= len(h.Memory) {
+		return 0, fmt.Errorf("fetch from 0x%X: %w", h.PC, ErrMemoryOutOfBounds)
+	}
+	res := h.GetOpcode(h.PC)
 	h.CurrentOpcode = res
 	h.PC += 2
-	return res
+	return res, nil
 }
 
+// Reads the opcode at pc without executing it. Addresses outside of memory read as 0
 func (h Emulator) GetOpcode(pc chip8.WORD) chip8.WORD {
-	return (chip8.WORD(h.Memory[pc]) << 8) | chip8.WORD(h.Memory[pc+1])
+	if int(pc)+1 >= len(h.Memory) {
+		return 0
+	}
+	return (chip8.WORD(h.Memory[pc]) << 8) | chip8.WORD(h.Memory[int(pc)+1])
+}
+
+// Playback rate of the audio pattern in bits per second, derived from the pitch register (XO-CHIP)
+func (h Emulator) AudioSampleRate() float64 {
+	return 4000 * math.Pow(2, (float64(h.Pitch)-64)/48)
 }
 
-func (h *Emulator) Step() {
-	op := h.GetNextOpcode()
+// Executes a single instruction, ticking the timers once InstructionsPerFrame
+// instructions have run in the current frame. Faults are returned as an *ExecutionError
+func (h *Emulator) Step() error {
+	if h.Exited {
+		return nil
+	}
+
+	// Execution is halted until the next vertical blank after a sprite draw
+	if h.waitVBlank {
+		h.endFrame()
+		return nil
+	}
 
+	if len(h.Breakpoints) > 0 {
+		if err := h.checkBreakpoints(); err != nil {
+			h.target = nil
+			return err
+		}
+	}
+
+	if h.frameCycle == 0 {
+		h.Input.Update(h.Frame)
+	}
+
+	pc := h.PC
+	registers, i := h.Registers, h.I
+	op, err := h.GetNextOpcode()
+	if err == nil {
+		if h.OnExecute != nil {
+			h.OnExecute(pc, op)
+		}
+		err = h.execute(op)
+	}
+	if err != nil {
+		h.PC = pc
+		h.watchHit = ""
+		h.target = nil
+		return &ExecutionError{PC: pc, Opcode: op, Err: err}
+	}
+
+	h.Cycles++
+	h.frameCycle++
+	if h.frameCycle >= h.InstructionsPerFrame {
+		h.endFrame()
+	}
+
+	if len(h.Watchpoints) > 0 {
+		h.checkRegisterWatch(registers, i)
+		if h.watchHit != "" {
+			reason := h.watchHit
+			h.watchHit = ""
+			h.target = nil
+			return &Break{PC: h.PC, Reason: reason}
+		}
+	}
+	return h.checkTarget()
+}
+
+// Executes instructions until the end of the current frame, when the timers tick.
+// Starting on a frame boundary this runs InstructionsPerFrame instructions
+func (h *Emulator) RunFrame() error {
+	frame := h.Frame
+	for h.Frame == frame && !h.Exited {
+		if err := h.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decodes and runs an opcode
+func (h *Emulator) execute(op chip8.WORD) error {
 	switch op & 0xF000 {
 	case 0x0000:
 		switch op & 0x00FF {
 		case 0x00E0:
 			h.Opcode00E0(op)
 		case 0x00EE:
-			h.Opcode00EE(op)
+			return h.Opcode00EE(op)
+		case 0x00FB:
+			h.Opcode00FB(op)
+		case 0x00FC:
+			h.Opcode00FC(op)
+		case 0x00FD:
+			h.Opcode00FD(op)
+		case 0x00FE:
+			h.Opcode00FE(op)
+		case 0x00FF:
+			h.Opcode00FF(op)
 		default:
-			h.Opcode0NNN(op)
+			if op&0xFFF0 == 0x00C0 {
+				h.Opcode00CN(op)
+			} else if op&0xFFF0 == 0x00D0 && h.Platform == PlatformXOChip {
+				h.Opcode00DN(op)
+			} else {
+				return h.Opcode0NNN(op)
+			}
 		}
 	case 0x1000:
 		h.Opcode1NNN(op)
 	case 0x2000:
-		h.Opcode2NNN(op)
+		return h.Opcode2NNN(op)
 	case 0x3000:
 		h.Opcode3XNN(op)
 	case 0x4000:
 		h.Opcode4XNN(op)
 	case 0x5000:
-		h.Opcode5XY0(op)
+		switch op & 0x000F {
+		case 0x0:
+			h.Opcode5XY0(op)
+		case 0x2:
+			if h.Platform != PlatformXOChip {
+				return ErrInvalidOpcode
+			}
+			return h.Opcode5XY2(op)
+		case 0x3:
+			if h.Platform != PlatformXOChip {
+				return ErrInvalidOpcode
+			}
+			return h.Opcode5XY3(op)
+		default:
+			return ErrInvalidOpcode
+		}
 	case 0x6000:
 		h.Opcode6XNN(op)
 	case 0x7000:
@@ -120,6 +469,8 @@ func (h *Emulator) Step() {
 			h.Opcode8XY7(op)
 		case 0xE:
 			h.Opcode8XYE(op)
+		default:
+			return ErrInvalidOpcode
 		}
 	case 0x9000:
 		h.Opcode9XY0(op)
@@ -130,16 +481,33 @@ func (h *Emulator) Step() {
 	case 0xC000:
 		h.OpcodeCXNN(op)
 	case 0xD000:
-		h.OpcodeDXYN(op)
+		return h.OpcodeDXYN(op)
 	case 0xE000:
 		switch op & 0x00FF {
 		case 0x9E:
 			h.OpcodeEX9E(op)
 		case 0xA1:
 			h.OpcodeEXA1(op)
+		default:
+			return ErrInvalidOpcode
 		}
 	case 0xF000:
 		switch op & 0x00FF {
+		case 0x00:
+			if op != 0xF000 || h.Platform != PlatformXOChip {
+				return ErrInvalidOpcode
+			}
+			return h.OpcodeF000(op)
+		case 0x01:
+			if h.Platform != PlatformXOChip {
+				return ErrInvalidOpcode
+			}
+			h.OpcodeFN01(op)
+		case 0x02:
+			if op != 0xF002 || h.Platform != PlatformXOChip {
+				return ErrInvalidOpcode
+			}
+			return h.OpcodeF002(op)
 		case 0x07:
 			h.OpcodeFX07(op)
 		case 0x0A:
@@ -152,50 +520,102 @@ func (h *Emulator) Step() {
 			h.OpcodeFX1E(op)
 		case 0x29:
 			h.OpcodeFX29(op)
+		case 0x30:
+			h.OpcodeFX30(op)
 		case 0x33:
-			h.OpcodeFX33(op)
+			return h.OpcodeFX33(op)
+		case 0x3A:
+			if h.Platform != PlatformXOChip {
+				return ErrInvalidOpcode
+			}
+			h.OpcodeFX3A(op)
 		case 0x55:
-			h.OpcodeFX55(op)
+			return h.OpcodeFX55(op)
 		case 0x65:
-			h.OpcodeFX65(op)
+			return h.OpcodeFX65(op)
+		case 0x75:
+			h.OpcodeFX75(op)
+		case 0x85:
+			h.OpcodeFX85(op)
+		default:
+			return ErrInvalidOpcode
 		}
-	default:
-		fmt.Printf("Something went wrong!\n")
 	}
+	return nil
+}
 
-	elapsedTime := time.Since(h.LastTick)
-	tickSpeed := time.Second / time.Duration(timerCap)
-	if elapsedTime > tickSpeed {
-		delta := elapsedTime - tickSpeed
-		h.LastTick = time.Now().Add(-delta)
-		if h.Delay > 0 {
-			h.Delay -= 1
-		}
-		if h.SoundDelay > 0 {
-			h.SoundDelay -= 1
-		}
+// Ticks the timers and starts the next frame
+func (h *Emulator) endFrame() {
+	if h.Delay > 0 {
+		h.Delay -= 1
+	}
+	if h.SoundDelay > 0 {
+		h.SoundDelay -= 1
+	}
+	h.updateBuzzer()
+	if h.Audio != nil {
+		h.Audio.EndFrame()
 	}
+	h.waitVBlank = false
+	h.frameCycle = 0
+	h.Frame++
+	h.Display.FrameReady()
 }
 
-// Call machine code routine at NNN
-func (h *Emulator) Opcode0NNN(op chip8.WORD) {
-	fmt.Println("0NNN Not implemented!")
+// Call machine code routine at NNN. Native routines can't be run so this always faults
+func (h *Emulator) Opcode0NNN(op chip8.WORD) error {
+	return ErrInvalidOpcode
 }
 
-// Clear the screen
+// Clear the selected planes of the screen
 func (h *Emulator) Opcode00E0(op chip8.WORD) {
-	for x := range h.ScreenData {
-		for y := range h.ScreenData[x] {
-			h.ScreenData[x][y] = 0
-		}
-	}
+	h.Display.Clear(h.Planes)
+}
+
+// Scroll the display down by N pixels (SUPER-CHIP)
+func (h *Emulator) Opcode00CN(op chip8.WORD) {
+	h.Display.Scroll(0, int(op&0x000F), h.Planes)
+}
+
+// Scroll the display up by N pixels (XO-CHIP)
+func (h *Emulator) Opcode00DN(op chip8.WORD) {
+	h.Display.Scroll(0, -int(op&0x000F), h.Planes)
+}
+
+// Scroll the display right by 4 pixels (SUPER-CHIP)
+func (h *Emulator) Opcode00FB(op chip8.WORD) {
+	h.Display.Scroll(4, 0, h.Planes)
+}
+
+// Scroll the display left by 4 pixels (SUPER-CHIP)
+func (h *Emulator) Opcode00FC(op chip8.WORD) {
+	h.Display.Scroll(-4, 0, h.Planes)
+}
+
+// Exit the interpreter (SUPER-CHIP)
+func (h *Emulator) Opcode00FD(op chip8.WORD) {
+	h.Exited = true
+}
+
+// Switch to the 64x32 low resolution mode (SUPER-CHIP)
+func (h *Emulator) Opcode00FE(op chip8.WORD) {
+	h.setResolution(false)
+}
+
+// Switch to the 128x64 high resolution mode (SUPER-CHIP)
+func (h *Emulator) Opcode00FF(op chip8.WORD) {
+	h.setResolution(true)
 }
 
 // Return from a subroutine
-func (h *Emulator) Opcode00EE(op chip8.WORD) {
+func (h *Emulator) Opcode00EE(op chip8.WORD) error {
+	if len(h.Stack) == 0 {
+		return ErrStackUnderflow
+	}
 	returnAddress := h.Stack[len(h.Stack)-1]
 	h.Stack = h.Stack[:len(h.Stack)-1]
 	h.PC = returnAddress
+	return nil
 }
 
 // Jump to address NNN
@@ -204,9 +624,13 @@ func (h *Emulator) Opcode1NNN(op chip8.WORD) {
 }
 
 // Call subroutine at NNN
-func (h *Emulator) Opcode2NNN(op chip8.WORD) {
+func (h *Emulator) Opcode2NNN(op chip8.WORD) error {
+	if len(h.Stack) >= stackSize {
+		return ErrStackOverflow
+	}
 	h.Stack = append(h.Stack, h.PC) // Save the program counter
 	h.PC = op & 0x0FFF              // Jump to address NNN
+	return nil
 }
 
 // Skips the next instruction if VX equals NN
@@ -214,7 +638,7 @@ func (h *Emulator) Opcode3XNN(op chip8.WORD) {
 	regx := (op & 0x0F00) >> 8
 	nn := op & 0x00FF
 	if chip8.WORD(h.Registers[regx]) == nn {
-		h.PC += 2
+		h.skipNextInstruction()
 	}
 }
 
@@ -223,7 +647,7 @@ func (h *Emulator) Opcode4XNN(op chip8.WORD) {
 	regx := (op & 0x0F00) >> 8
 	nn := op & 0x00FF
 	if chip8.WORD(h.Registers[regx]) != nn {
-		h.PC += 2
+		h.skipNextInstruction()
 	}
 }
 
@@ -231,8 +655,46 @@ func (h *Emulator) Opcode4XNN(op chip8.WORD) {
 func (h *Emulator) Opcode5XY0(op chip8.WORD) {
 	regx, regy := chip8.GetXYReg(op)
 	if h.Registers[regx] == h.Registers[regy] {
-		h.PC += 2
+		h.skipNextInstruction()
+	}
+}
+
+// Stores VX to VY (including VY, in either order) in memory, starting at address I. I is not changed (XO-CHIP)
+func (h *Emulator) Opcode5XY2(op chip8.WORD) error {
+	regx, regy := chip8.GetXYReg(op)
+	for i, reg := range registerRange(regx, regy) {
+		if err := h.writeMemory(int(h.I)+i, h.Registers[reg]); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// Fills VX to VY (including VY, in either order) with values from memory, starting at address I. I is not changed (XO-CHIP)
+func (h *Emulator) Opcode5XY3(op chip8.WORD) error {
+	regx, regy := chip8.GetXYReg(op)
+	for i, reg := range registerRange(regx, regy) {
+		value, err := h.readMemory(int(h.I) + i)
+		if err != nil {
+			return err
+		}
+		h.Registers[reg] = value
+	}
+	return nil
+}
+
+// Lists the registers from X to Y inclusive, counting down when Y is less than X
+func registerRange(regx, regy chip8.WORD) []chip8.WORD {
+	regs := []chip8.WORD{regx}
+	for reg := regx; reg != regy; {
+		if regx < regy {
+			reg++
+		} else {
+			reg--
+		}
+		regs = append(regs, reg)
+	}
+	return regs
 }
 
 // Sets VX to NN
@@ -257,73 +719,94 @@ func (h *Emulator) Opcode8XY0(op chip8.WORD) {
 func (h *Emulator) Opcode8XY1(op chip8.WORD) {
 	regx, regy := chip8.GetXYReg(op)
 	h.Registers[regx] = h.Registers[regx] | h.Registers[regy]
+	if h.Quirks.VFReset {
+		h.Registers[0xF] = 0
+	}
 }
 
 // Sets VX to VX and VY (bitwise AND operation)
 func (h *Emulator) Opcode8XY2(op chip8.WORD) {
 	regx, regy := chip8.GetXYReg(op)
 	h.Registers[regx] = h.Registers[regx] & h.Registers[regy]
+	if h.Quirks.VFReset {
+		h.Registers[0xF] = 0
+	}
 }
 
 // Sets VX to VX xor VY
 func (h *Emulator) Opcode8XY3(op chip8.WORD) {
 	regx, regy := chip8.GetXYReg(op)
 	h.Registers[regx] = h.Registers[regx] ^ h.Registers[regy]
+	if h.Quirks.VFReset {
+		h.Registers[0xF] = 0
+	}
 }
 
-// Adds VY to VX. VF is set to 1 when there's a carry, and to 0 when there is not
+// Adds VY to VX. VF is set to 1 when there's a carry, and to 0 when there is not.
+// Like the other arithmetic opcodes VF is written after VX, so the flag is kept when X is F
 func (h *Emulator) Opcode8XY4(op chip8.WORD) {
 	regx, regy := chip8.GetXYReg(op)
-	if h.Registers[regx]+h.Registers[regy] > 255 {
+	sum := int(h.Registers[regx]) + int(h.Registers[regy])
+	h.Registers[regx] = chip8.BYTE(sum)
+	h.Registers[0xF] = 0
+	if sum > 0xFF {
 		h.Registers[0xF] = 1
-	} else {
-		h.Registers[0xF] = 0
 	}
-	h.Registers[regx] += h.Registers[regy]
 }
 
 // VY is subtracted from VX. VF is set to 0 when there's a borrow, and 1 when there is not
 func (h *Emulator) Opcode8XY5(op chip8.WORD) {
-	h.Registers[0xF] = 1
 	regx, regy := chip8.GetXYReg(op)
 	xVal := h.Registers[regx]
 	yVal := h.Registers[regy]
+	h.Registers[regx] = xVal - yVal
+	h.Registers[0xF] = 1
 	if yVal > xVal { // If this is true will result in a value < 0
 		h.Registers[0xF] = 0
 	}
-	h.Registers[regx] = xVal - yVal
 }
 
-// Stores the least significant bit of VX in VF and then shifts VX to the right by 1
+// Stores the least significant bit of VY in VF and then stores VY shifted to the right by 1 in VX.
+// With the shift quirk VX is shifted in place instead
 func (h *Emulator) Opcode8XY6(op chip8.WORD) {
-	regx, _ := chip8.GetXYReg(op)
-	h.Registers[0xF] = h.Registers[regx] & 1
-	h.Registers[regx] >>= 1
+	regx, regy := chip8.GetXYReg(op)
+	if h.Quirks.Shift {
+		regy = regx
+	}
+	flag := h.Registers[regy] & 1
+	h.Registers[regx] = h.Registers[regy] >> 1
+	h.Registers[0xF] = flag
 }
 
 // Sets VX to VY minus VX. VF is set to 0 when there's a borrow, and 1 when there is not
 func (h *Emulator) Opcode8XY7(op chip8.WORD) {
 	regx, regy := chip8.GetXYReg(op)
-	if h.Registers[regx] > h.Registers[regy] {
+	xVal := h.Registers[regx]
+	yVal := h.Registers[regy]
+	h.Registers[regx] = yVal - xVal
+	h.Registers[0xF] = 1
+	if xVal > yVal {
 		h.Registers[0xF] = 0
-	} else {
-		h.Registers[0xF] = 1
 	}
-	h.Registers[regx] = h.Registers[regy] - h.Registers[regx]
 }
 
-// Stores the most significant bit of VX in VF and then shifts VX to the left by 1
+// Stores the most significant bit of VY in VF and then stores VY shifted to the left by 1 in VX.
+// With the shift quirk VX is shifted in place instead
 func (h *Emulator) Opcode8XYE(op chip8.WORD) {
-	regx, _ := chip8.GetXYReg(op)
-	h.Registers[0xF] = h.Registers[regx] & 0x80
-	h.Registers[regx] <<= 1
+	regx, regy := chip8.GetXYReg(op)
+	if h.Quirks.Shift {
+		regy = regx
+	}
+	flag := h.Registers[regy] >> 7
+	h.Registers[regx] = h.Registers[regy] << 1
+	h.Registers[0xF] = flag
 }
 
 // Skips the next instruction if VX does not equal VY. (Usually the next instruction is a jump to skip a code block);
 func (h *Emulator) Opcode9XY0(op chip8.WORD) {
 	regx, regy := chip8.GetXYReg(op)
 	if h.Registers[regx] != h.Registers[regy] {
-		h.PC += 2
+		h.skipNextInstruction()
 	}
 }
 
@@ -332,76 +815,79 @@ func (h *Emulator) OpcodeANNN(op chip8.WORD) {
 	h.I = op & 0x0FFF
 }
 
-// Jumps to the address NNN plus V0.
+// Jumps to the address NNN plus V0. With the jump quirk this jumps to XNN plus VX
 func (h *Emulator) OpcodeBNNN(op chip8.WORD) {
-	h.PC = (op & 0x0FFF) + chip8.WORD(h.Registers[0])
+	reg := chip8.WORD(0)
+	if h.Quirks.Jump {
+		reg, _ = chip8.GetXYReg(op)
+	}
+	h.PC = (op & 0x0FFF) + chip8.WORD(h.Registers[reg])
 }
 
 // Sets VX to the result of a bitwise and operation on a random number (Typically: 0 to 255) and NN.
 func (h *Emulator) OpcodeCXNN(op chip8.WORD) {
-	h.Registers[(op&0x0F00)>>8] = chip8.BYTE(int((op & 0x00FF)) & rand.Intn(254))
+	h.Registers[(op&0x0F00)>>8] = chip8.BYTE(op&0x00FF) & chip8.BYTE(h.rng.Uint64()>>56)
 }
 
 // Draws a sprite at coordinate (VX, VY) that has a width of 8 pixels and a height of N pixels. Each row of 8 pixels is read as bit-coded starting from memory location I; I value does not change after the execution of this instruction. As described above, VF is set to 1 if any screen pixels are flipped from set to unset when the sprite is drawn, and to 0 if that does not happen.
-func (h *Emulator) OpcodeDXYN(op chip8.WORD) {
+// When N is 0 a 16x16 sprite is drawn instead, read as two bytes per row (SUPER-CHIP).
+// With several planes selected the sprite for each plane follows the previous one in memory (XO-CHIP).
+func (h *Emulator) OpcodeDXYN(op chip8.WORD) error {
 	regx, regy := chip8.GetXYReg(op)
 
-	height := op & 0x000F
-	coordx := h.Registers[regx]
-	coordy := h.Registers[regy]
+	width, height := 8, int(op&0x000F)
+	if height == 0 {
+		width, height = 16, 16
+	}
+	spriteSize := height * width / 8
+	coordx := int(h.Registers[regx]) % h.ScreenWidth()
+	coordy := int(h.Registers[regy]) % h.ScreenHeight()
 
 	h.Registers[0xF] = 0
-
-	// Clear out any leftovers
-	for x := range h.ScreenData {
-		for y := range h.ScreenData[x] {
-			if h.ScreenData[x][y] == 2 {
-				h.ScreenData[x][y] = 0
-			}
+	addr := int(h.I)
+	rows := make([]chip8.WORD, height)
+	for plane := chip8.BYTE(1); plane <= 2; plane <<= 1 {
+		if h.Planes&plane == 0 {
+			continue
 		}
-	}
 
-	// Loop for the amount of vertical lines needed to draw this
-	var yline chip8.WORD
-	for yline = 0; yline < height; yline++ {
-		data := h.Memory[h.I+yline]
-		xpixelinv := 7
-		xpixel := 0
-		for xpixel = 0; xpixel < 8; {
-			mask := chip8.BYTE(1 << xpixelinv)
-			if data&mask > 0 {
-				x := (int(coordx) + xpixel) % 64
-				y := (chip8.WORD(coordy) + yline) % 32
-
-				if h.ScreenData[x][y] == 1 {
-					h.Registers[0xF] = 1 // Collision
-					h.ScreenData[x][y] = 2
-				} else {
-					h.ScreenData[x][y] ^= 1
+		// Rows are left aligned in a 16 bit value so both sprite widths share the same mask
+		for yline := range rows {
+			rows[yline] = 0
+			for i := 0; i < width/8; i++ {
+				b, err := h.readMemory(addr + yline*width/8 + i)
+				if err != nil {
+					return err
 				}
+				rows[yline] |= chip8.WORD(b) << (8 - 8*i)
 			}
-			xpixel++
-			xpixelinv--
 		}
+
+		if h.Display.DrawSprite(coordx, coordy, rows, width, plane, h.Quirks.Clipping) {
+			h.Registers[0xF] = 1 // Collision
+		}
+		addr += spriteSize
 	}
+
+	if h.Quirks.DisplayWait {
+		h.waitVBlank = true
+	}
+	return nil
 }
 
 // Skips the next instruction if the key stored in VX is pressed (usually the next instruction is a jump to skip a code block).
 func (h *Emulator) OpcodeEX9E(op chip8.WORD) {
 	regx := (op & 0x0F00) >> 8
-	if h.Inputs[h.Registers[regx]] != 0 {
-		h.PC += 2
-		h.Inputs[h.Registers[regx]] = 0
+	if h.Input.Pressed(int(h.Registers[regx] & 0xF)) {
+		h.skipNextInstruction()
 	}
 }
 
 // Skips the next instruction if the key stored in VX is not pressed (usually the next instruction is a jump to skip a code block).
 func (h *Emulator) OpcodeEXA1(op chip8.WORD) {
 	regx := (op & 0x0F00) >> 8
-	if h.Inputs[h.Registers[regx]] == 0 {
-		h.PC += 2
-	} else {
-		h.Inputs[h.Registers[regx]] = 0
+	if !h.Input.Pressed(int(h.Registers[regx] & 0xF)) {
+		h.skipNextInstruction()
 	}
 }
 
@@ -411,9 +897,59 @@ func (h *Emulator) OpcodeFX07(op chip8.WORD) {
 	h.Registers[regx] = h.Delay
 }
 
+// Sets I to the 16 bit address stored in the next word, skipping over it (XO-CHIP)
+func (h *Emulator) OpcodeF000(op chip8.WORD) error {
+	addr, err := h.GetNextOpcode()
+	if err != nil {
+		return err
+	}
+	h.CurrentOpcode = op
+	h.I = addr
+	return nil
+}
+
+// Selects the bitplanes N that are drawn to, cleared and scrolled (XO-CHIP)
+func (h *Emulator) OpcodeFN01(op chip8.WORD) {
+	h.Planes = chip8.BYTE((op&0x0F00)>>8) & 0x3
+}
+
+// Loads the 16 byte audio pattern buffer from memory, starting at address I (XO-CHIP)
+func (h *Emulator) OpcodeF002(op chip8.WORD) error {
+	for i := range h.AudioPattern {
+		value, err := h.readMemory(int(h.I) + i)
+		if err != nil {
+			return err
+		}
+		h.AudioPattern[i] = value
+	}
+	return nil
+}
+
 // A key press is awaited, and then stored in VX (blocking operation, all instruction halted until next key event).
+// The key is stored once it has been released again. Timers keep counting down while waiting.
 func (h *Emulator) OpcodeFX0A(op chip8.WORD) {
-	fmt.Println("FX0A Not implemented!")
+	regx, _ := chip8.GetXYReg(op)
+	if !h.WaitingForKey {
+		h.WaitingForKey = true
+		h.waitKey = -1
+	}
+
+	if h.waitKey == -1 {
+		for key := 0; key < 16; key++ {
+			if h.Input.Pressed(key) {
+				h.waitKey = key
+				break
+			}
+		}
+	} else if !h.Input.Pressed(h.waitKey) {
+		h.Registers[regx] = chip8.BYTE(h.waitKey)
+		h.WaitingForKey = false
+		h.waitKey = -1
+		return
+	}
+
+	// Execute this instruction again until the key is released
+	h.PC -= 2
 }
 
 // Sets the delay timer to VX.
@@ -426,27 +962,29 @@ func (h *Emulator) OpcodeFX15(op chip8.WORD) {
 func (h *Emulator) OpcodeFX18(op chip8.WORD) {
 	regx, _ := chip8.GetXYReg(op)
 	h.SoundDelay = h.Registers[regx]
+	h.updateBuzzer()
 }
 
 // Adds VX to I. VF is not affected
 func (h *Emulator) OpcodeFX1E(op chip8.WORD) {
 	regx := (op & 0x0F00) >> 8
-	if h.I+chip8.WORD(h.Registers[regx]) > 0xFFF {
-		h.Registers[0xF] = 1
-	} else {
-		h.Registers[0xF] = 0
-	}
 	h.I += chip8.WORD(h.Registers[regx])
 }
 
 // Sets I to the location of the sprite for the character in VX. Characters 0-F (in hexadecimal) are represented by a 4x5 font.
 func (h *Emulator) OpcodeFX29(op chip8.WORD) {
 	regx := (op & 0x0F00) >> 8
-	h.I = chip8.WORD(h.Registers[regx]) * 0x5
+	h.I = h.FontAddress + chip8.WORD(h.Registers[regx]&0xF)*0x5
+}
+
+// Sets I to the location of the 8x10 sprite for the character in VX (SUPER-CHIP).
+func (h *Emulator) OpcodeFX30(op chip8.WORD) {
+	regx := (op & 0x0F00) >> 8
+	h.I = h.bigFontAddress() + chip8.WORD(h.Registers[regx]&0xF)*10
 }
 
 // Stores the binary-coded decimal representation of VX, with the hundreds digit in memory at location in I, the tens digit at location I+1, and the ones digit at location I+2.
-func (h *Emulator) OpcodeFX33(op chip8.WORD) {
+func (h *Emulator) OpcodeFX33(op chip8.WORD) error {
 	regx := (op & 0x0F00) >> 8
 	value := h.Registers[regx]
 
@@ -454,25 +992,62 @@ func (h *Emulator) OpcodeFX33(op chip8.WORD) {
 	tens := (value / 10) % 10
 	units := value % 10
 
-	h.Memory[h.I] = hundreds
-	h.Memory[h.I+1] = tens
-	h.Memory[h.I+2] = units
+	for i, digit := range []chip8.BYTE{hundreds, tens, units} {
+		if err := h.writeMemory(int(h.I)+i, digit); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Stores from V0 to VX (including VX) in memory, starting at address I
-func (h *Emulator) OpcodeFX55(op chip8.WORD) {
+func (h *Emulator) OpcodeFX55(op chip8.WORD) error {
 	regx := (op & 0x0F00) >> 8
 	for i := 0; chip8.WORD(i) <= regx; i++ {
-		h.Memory[h.I+chip8.WORD(i)] = h.Registers[i]
+		if err := h.writeMemory(int(h.I)+i, h.Registers[i]); err != nil {
+			return err
+		}
+	}
+	if h.Quirks.LoadStore {
+		h.I = h.I + regx + 1
 	}
-	h.I = h.I + regx + 1
+	return nil
 }
 
 // Fills from V0 to VX (including VX) with values from memory, starting at address I
-func (h *Emulator) OpcodeFX65(op chip8.WORD) {
+func (h *Emulator) OpcodeFX65(op chip8.WORD) error {
+	regx := (op & 0x0F00) >> 8
+	for i := 0; i <= int(regx); i++ {
+		value, err := h.readMemory(int(h.I) + i)
+		if err != nil {
+			return err
+		}
+		h.Registers[i] = value
+	}
+	if h.Quirks.LoadStore {
+		h.I = h.I + regx + 1
+	}
+	return nil
+}
+
+// Sets the audio pattern playback pitch to VX (XO-CHIP)
+func (h *Emulator) OpcodeFX3A(op chip8.WORD) {
+	regx := (op & 0x0F00) >> 8
+	h.Pitch = h.Registers[regx]
+}
+
+// Stores V0 to VX (including VX) in the RPL user flags (SUPER-CHIP)
+func (h *Emulator) OpcodeFX75(op chip8.WORD) {
+	regx := (op & 0x0F00) >> 8
+	for i := 0; i <= int(regx); i++ {
+		h.RPLFlags[i] = h.Registers[i]
+	}
+}
+
+// Fills V0 to VX (including VX) from the RPL user flags (SUPER-CHIP)
+func (h *Emulator) OpcodeFX85(op chip8.WORD) {
 	regx := (op & 0x0F00) >> 8
 	for i := 0; i <= int(regx); i++ {
-		h.Registers[i] = h.Memory[int(h.I)+i]
+		h.Registers[i] = h.RPLFlags[i]
 	}
-	h.I = h.I + regx + 1
 }
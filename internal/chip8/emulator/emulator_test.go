@@ -0,0 +1,231 @@
+package emulator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+)
+
+// Loads the opcodes as a ROM with the given quirks
+func newTestEmulator(t *testing.T, quirks Quirks, program ...chip8.WORD) *Emulator {
+	t.Helper()
+	rom := make([]byte, 0, len(program)*2)
+	for _, op := range program {
+		rom = append(rom, byte(op>>8), byte(op))
+	}
+	h, err := NewEmulatorFromBytes(rom, WithQuirks(quirks), WithSeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// Executes n instructions
+func step(t *testing.T, h *Emulator, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := h.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestArithmeticFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		op     chip8.WORD
+		x, y   chip8.BYTE
+		wantX  chip8.BYTE
+		wantVF chip8.BYTE
+	}{
+		{"8XY4 no carry", 0x8014, 0x10, 0x20, 0x30, 0},
+		{"8XY4 carry", 0x8014, 0xF0, 0x20, 0x10, 1},
+		{"8XY4 exactly 256", 0x8014, 0x80, 0x80, 0x00, 1},
+		{"8XY5 no borrow", 0x8015, 0x30, 0x10, 0x20, 1},
+		{"8XY5 equal", 0x8015, 0x30, 0x30, 0x00, 1},
+		{"8XY5 borrow", 0x8015, 0x10, 0x30, 0xE0, 0},
+		{"8XY6 low bit set", 0x8016, 0, 0x05, 0x02, 1},
+		{"8XY6 low bit clear", 0x8016, 0, 0x04, 0x02, 0},
+		{"8XY7 no borrow", 0x8017, 0x10, 0x30, 0x20, 1},
+		{"8XY7 borrow", 0x8017, 0x30, 0x10, 0xE0, 0},
+		{"8XYE high bit set", 0x801E, 0, 0x81, 0x02, 1},
+		{"8XYE high bit clear", 0x801E, 0, 0x41, 0x82, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestEmulator(t, QuirksVIP, tt.op)
+			h.Registers[0] = tt.x
+			h.Registers[1] = tt.y
+			step(t, h, 1)
+			if h.Registers[0] != tt.wantX {
+				t.Errorf("V0 = 0x%02X, want 0x%02X", h.Registers[0], tt.wantX)
+			}
+			if h.Registers[0xF] != tt.wantVF {
+				t.Errorf("VF = %d, want %d", h.Registers[0xF], tt.wantVF)
+			}
+		})
+	}
+}
+
+// With VF as the destination the flag overwrites the result
+func TestArithmeticFlagsIntoVF(t *testing.T) {
+	tests := []struct {
+		name   string
+		op     chip8.WORD
+		vf, y  chip8.BYTE
+		wantVF chip8.BYTE
+	}{
+		{"8XY4", 0x8F14, 0xF0, 0x20, 1},
+		{"8XY5", 0x8F15, 0x10, 0x30, 0},
+		{"8XY6", 0x8F16, 0, 0x05, 1},
+		{"8XY7", 0x8F17, 0x10, 0x30, 1},
+		{"8XYE", 0x8F1E, 0, 0x41, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestEmulator(t, QuirksVIP, tt.op)
+			h.Registers[0xF] = tt.vf
+			h.Registers[1] = tt.y
+			step(t, h, 1)
+			if h.Registers[0xF] != tt.wantVF {
+				t.Errorf("VF = %d, want %d", h.Registers[0xF], tt.wantVF)
+			}
+		})
+	}
+}
+
+func TestFX1ELeavesVF(t *testing.T) {
+	h := newTestEmulator(t, QuirksVIP, 0xAFFF, 0xF01E)
+	h.Registers[0] = 0x10
+	h.Registers[0xF] = 0x42
+	step(t, h, 2)
+	if h.I != 0x100F {
+		t.Errorf("I = 0x%04X, want 0x100F", h.I)
+	}
+	if h.Registers[0xF] != 0x42 {
+		t.Errorf("VF = 0x%02X, want it left at 0x42", h.Registers[0xF])
+	}
+}
+
+func TestQuirks(t *testing.T) {
+	tests := []struct {
+		name    string
+		quirks  Quirks
+		program []chip8.WORD
+		steps   int
+		check   func(h *Emulator) bool
+	}{
+		{
+			name:    "VF reset",
+			quirks:  Quirks{VFReset: true},
+			program: []chip8.WORD{0x6F05, 0x8011},
+			steps:   2,
+			check:   func(h *Emulator) bool { return h.Registers[0xF] == 0 },
+		},
+		{
+			name:    "no VF reset",
+			quirks:  Quirks{},
+			program: []chip8.WORD{0x6F05, 0x8011},
+			steps:   2,
+			check:   func(h *Emulator) bool { return h.Registers[0xF] == 5 },
+		},
+		{
+			name:    "shift VY",
+			quirks:  Quirks{},
+			program: []chip8.WORD{0x6004, 0x6110, 0x8016},
+			steps:   3,
+			check:   func(h *Emulator) bool { return h.Registers[0] == 0x08 },
+		},
+		{
+			name:    "shift VX in place",
+			quirks:  Quirks{Shift: true},
+			program: []chip8.WORD{0x6004, 0x6110, 0x8016},
+			steps:   3,
+			check:   func(h *Emulator) bool { return h.Registers[0] == 0x02 },
+		},
+		{
+			name:    "jump V0",
+			quirks:  Quirks{},
+			program: []chip8.WORD{0x6002, 0x6204, 0xB230},
+			steps:   3,
+			check:   func(h *Emulator) bool { return h.PC == 0x232 },
+		},
+		{
+			name:    "jump VX",
+			quirks:  Quirks{Jump: true},
+			program: []chip8.WORD{0x6002, 0x6204, 0xB230},
+			steps:   3,
+			check:   func(h *Emulator) bool { return h.PC == 0x234 },
+		},
+		{
+			name:    "load store increments I",
+			quirks:  Quirks{LoadStore: true},
+			program: []chip8.WORD{0xA300, 0xF255},
+			steps:   2,
+			check:   func(h *Emulator) bool { return h.I == 0x303 },
+		},
+		{
+			name:    "load store leaves I",
+			quirks:  Quirks{},
+			program: []chip8.WORD{0xA300, 0xF265},
+			steps:   2,
+			check:   func(h *Emulator) bool { return h.I == 0x300 },
+		},
+		{
+			name:    "clipping",
+			quirks:  Quirks{Clipping: true},
+			program: []chip8.WORD{0x603D, 0xA000, 0xD011},
+			steps:   3,
+			check:   func(h *Emulator) bool { return h.Display.Pixel(61, 0) == 1 && h.Display.Pixel(0, 0) == 0 },
+		},
+		{
+			name:    "wrapping",
+			quirks:  Quirks{},
+			program: []chip8.WORD{0x603D, 0xA000, 0xD011},
+			steps:   3,
+			check:   func(h *Emulator) bool { return h.Display.Pixel(61, 0) == 1 && h.Display.Pixel(0, 0) == 1 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestEmulator(t, tt.quirks, tt.program...)
+			step(t, h, tt.steps)
+			if !tt.check(h) {
+				t.Errorf("unexpected state: V=%v I=0x%04X PC=0x%04X", h.Registers, h.I, h.PC)
+			}
+		})
+	}
+}
+
+func TestFX33(t *testing.T) {
+	h := newTestEmulator(t, QuirksVIP, 0x60FE, 0xA300, 0xF033)
+	step(t, h, 3)
+	if got := h.Memory[0x300:0x303]; got[0] != 2 || got[1] != 5 || got[2] != 4 {
+		t.Errorf("BCD of 254 = %v, want [2 5 4]", got)
+	}
+}
+
+func TestStackErrors(t *testing.T) {
+	h := newTestEmulator(t, QuirksVIP, 0x00EE)
+	if err := h.Step(); !errors.Is(err, ErrStackUnderflow) {
+		t.Errorf("00EE on an empty stack: got %v, want ErrStackUnderflow", err)
+	}
+
+	// Calls itself forever
+	h = newTestEmulator(t, QuirksVIP, 0x2200)
+	var err error
+	for i := 0; i <= stackSize && err == nil; i++ {
+		err = h.Step()
+	}
+	if !errors.Is(err, ErrStackOverflow) {
+		t.Errorf("recursive 2NNN: got %v, want ErrStackOverflow", err)
+	}
+	var execErr *ExecutionError
+	if !errors.As(err, &execErr) || execErr.PC != 0x200 || execErr.Opcode != 0x2200 {
+		t.Errorf("got %v, want an ExecutionError for 0x2200 at 0x200", err)
+	}
+}
@@ -0,0 +1,56 @@
+package emulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+)
+
+var (
+	ErrStackUnderflow    = errors.New("stack underflow")
+	ErrStackOverflow     = errors.New("stack overflow")
+	ErrMemoryOutOfBounds = errors.New("memory access out of bounds")
+	ErrInvalidOpcode     = errors.New("invalid opcode")
+	ErrROMTooLarge       = errors.New("rom does not fit in memory")
+)
+
+// ExecutionError is returned by Step when an instruction faults. PC is left
+// pointing at the faulting instruction.
+type ExecutionError struct {
+	PC     chip8.WORD
+	Opcode chip8.WORD
+	Err    error
+}
+
+func (e *ExecutionError) Error() string {
+	return fmt.Sprintf("opcode 0x%04X at 0x%04X: %v", e.Opcode, e.PC, e.Err)
+}
+
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// Maximum depth of the call stack, matching SUPER-CHIP
+const stackSize = 16
+
+func (h *Emulator) readMemory(addr int) (chip8.BYTE, error) {
+	if addr < 0 || addr >= len(h.Memory) {
+		return 0, fmt.Errorf("read from 0x%X: %w", addr, ErrMemoryOutOfBounds)
+	}
+	if len(h.Watchpoints) > 0 {
+		h.checkMemoryWatch(addr, WatchRead)
+	}
+	return h.Memory[addr], nil
+}
+
+func (h *Emulator) writeMemory(addr int, value chip8.BYTE) error {
+	if addr < 0 || addr >= len(h.Memory) {
+		return fmt.Errorf("write to 0x%X: %w", addr, ErrMemoryOutOfBounds)
+	}
+	if len(h.Watchpoints) > 0 {
+		h.checkMemoryWatch(addr, WatchWrite)
+	}
+	h.Memory[addr] = value
+	return nil
+}
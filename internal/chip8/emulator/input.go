@@ -0,0 +1,205 @@
+package emulator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// InputSource reports the state of the 16 key hex keypad. The emulator reads it
+// live from EX9E, EXA1 and FX0A.
+type InputSource interface {
+	// Called with the frame number before the first instruction of every 60Hz
+	// frame. It can be called more than once for the same frame, and the frame
+	// goes backwards after a reset or when a save state is loaded.
+	Update(frame uint64)
+
+	// Reports whether key (0-F) is held down
+	Pressed(key int) bool
+}
+
+// Keypad is an InputSource driven by key down and up events, for frontends that get both
+type Keypad struct {
+	keys [16]bool
+}
+
+func NewKeypad() *Keypad {
+	return &Keypad{}
+}
+
+func (k *Keypad) Down(key int) {
+	k.keys[key&0xF] = true
+}
+
+func (k *Keypad) Up(key int) {
+	k.keys[key&0xF] = false
+}
+
+func (k *Keypad) Update(frame uint64) {}
+
+func (k *Keypad) Pressed(key int) bool {
+	return k.keys[key&0xF]
+}
+
+// DecayKeypad is an InputSource for frontends that only see key presses, such as
+// terminals. Each press holds the key down for Hold frames, and the terminal's key
+// repeat keeps a held key down as long as Hold covers the gap between repeats.
+type DecayKeypad struct {
+	Hold uint64
+
+	frame uint64
+	until [16]uint64 // Frame each key is released on
+}
+
+func NewDecayKeypad(hold uint64) *DecayKeypad {
+	return &DecayKeypad{Hold: hold}
+}
+
+func (k *DecayKeypad) Press(key int) {
+	k.until[key&0xF] = k.frame + k.Hold
+}
+
+// Releases every key
+func (k *DecayKeypad) Release() {
+	k.until = [16]uint64{}
+}
+
+func (k *DecayKeypad) Update(frame uint64) {
+	// Keys pressed in the future of a rewind would otherwise stay down for too long
+	if frame < k.frame {
+		k.Release()
+	}
+	k.frame = frame
+}
+
+func (k *DecayKeypad) Pressed(key int) bool {
+	return k.frame < k.until[key&0xF]
+}
+
+// InputEvent is a key going down or up at the start of a frame
+type InputEvent struct {
+	Frame uint64
+	Key   int
+	Down  bool
+}
+
+// ScriptedInput is an InputSource that plays back events, such as ones recorded
+// by an InputRecorder. The events don't need to be in order.
+type ScriptedInput struct {
+	Events []InputEvent
+
+	frame uint64
+}
+
+func NewScriptedInput(events []InputEvent) *ScriptedInput {
+	return &ScriptedInput{Events: events}
+}
+
+// Adds events holding key down from frame for hold frames
+func (s *ScriptedInput) Press(frame uint64, key int, hold uint64) {
+	s.Events = append(s.Events,
+		InputEvent{Frame: frame, Key: key, Down: true},
+		InputEvent{Frame: frame + hold, Key: key, Down: false},
+	)
+}
+
+func (s *ScriptedInput) Update(frame uint64) {
+	s.frame = frame
+}
+
+// Reports the state set by the key's latest event up to the current frame
+func (s *ScriptedInput) Pressed(key int) bool {
+	down, latest := false, uint64(0)
+	for _, e := range s.Events {
+		if e.Key == key&0xF && e.Frame <= s.frame && e.Frame >= latest {
+			down, latest = e.Down, e.Frame
+		}
+	}
+	return down
+}
+
+// InputRecorder passes another InputSource through, recording every key it
+// reports going down or up so the run can be played back with ScriptedInput
+type InputRecorder struct {
+	Source InputSource
+	Events []InputEvent
+
+	keys [16]bool
+}
+
+func NewInputRecorder(source InputSource) *InputRecorder {
+	return &InputRecorder{Source: source}
+}
+
+func (r *InputRecorder) Update(frame uint64) {
+	r.Source.Update(frame)
+
+	// After a reset or rewind the recording continues from the earlier frame
+	if len(r.Events) > 0 && r.Events[len(r.Events)-1].Frame > frame {
+		for len(r.Events) > 0 && r.Events[len(r.Events)-1].Frame > frame {
+			r.Events = r.Events[:len(r.Events)-1]
+		}
+		r.keys = [16]bool{}
+		for _, e := range r.Events {
+			r.keys[e.Key] = e.Down
+		}
+	}
+
+	for key := range r.keys {
+		down := r.Source.Pressed(key)
+		if down != r.keys[key] {
+			r.keys[key] = down
+			r.Events = append(r.Events, InputEvent{Frame: frame, Key: key, Down: down})
+		}
+	}
+}
+
+func (r *InputRecorder) Pressed(key int) bool {
+	return r.Source.Pressed(key)
+}
+
+// Writes events as an input script, one "FRAME KEY down|up" line each
+func WriteInputScript(w io.Writer, events []InputEvent) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range events {
+		state := "up"
+		if e.Down {
+			state = "down"
+		}
+		fmt.Fprintf(bw, "%d %X %s\n", e.Frame, e.Key, state)
+	}
+	return bw.Flush()
+}
+
+// Reads an input script written by WriteInputScript. Blank lines and lines
+// starting with # are ignored.
+func ReadInputScript(r io.Reader) ([]InputEvent, error) {
+	events := []InputEvent{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected FRAME KEY down|up", line)
+		}
+		frame, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid frame %q", line, fields[0])
+		}
+		key, err := strconv.ParseUint(fields[1], 16, 4)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid key %q", line, fields[1])
+		}
+		if fields[2] != "down" && fields[2] != "up" {
+			return nil, fmt.Errorf("line %d: expected down or up, got %q", line, fields[2])
+		}
+		events = append(events, InputEvent{Frame: frame, Key: int(key), Down: fields[2] == "down"})
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,121 @@
+package emulator
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFX0AWaitsForRelease(t *testing.T) {
+	keypad := NewKeypad()
+	h, err := NewEmulatorFromBytes([]byte{0xF3, 0x0A, 0x12, 0x02}, WithInput(keypad))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step(t, h, 3)
+	if !h.WaitingForKey || h.PC != 0x200 {
+		t.Fatal("FX0A did not wait for a key")
+	}
+
+	keypad.Down(0xB)
+	step(t, h, 3)
+	if !h.WaitingForKey {
+		t.Fatal("FX0A completed before the key was released")
+	}
+
+	keypad.Up(0xB)
+	step(t, h, 1)
+	if h.WaitingForKey || h.Registers[3] != 0xB || h.PC != 0x202 {
+		t.Errorf("after release: waiting=%v V3=0x%X PC=0x%04X", h.WaitingForKey, h.Registers[3], h.PC)
+	}
+}
+
+func TestHeldKeyStaysDown(t *testing.T) {
+	keypad := NewKeypad()
+	// V1 counts the EX9E skips: SKP V0, JMP 0x206, ADD V1 1, JMP 0x200
+	h, err := NewEmulatorFromBytes([]byte{0xE0, 0x9E, 0x12, 0x06, 0x71, 0x01, 0x12, 0x00}, WithInput(keypad))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keypad.Down(0)
+	step(t, h, 9)
+	if h.Registers[1] != 3 {
+		t.Errorf("key seen pressed %d times in 3 loops, want 3", h.Registers[1])
+	}
+}
+
+func TestDecayKeypad(t *testing.T) {
+	k := NewDecayKeypad(3)
+	k.Update(10)
+	k.Press(5)
+	for frame := uint64(10); frame < 13; frame++ {
+		k.Update(frame)
+		if !k.Pressed(5) {
+			t.Fatalf("key released early at frame %d", frame)
+		}
+	}
+	k.Update(13)
+	if k.Pressed(5) {
+		t.Error("key still down after the hold")
+	}
+
+	// A rewind releases keys pressed later on
+	k.Press(5)
+	k.Update(2)
+	if k.Pressed(5) {
+		t.Error("key still down after going back in time")
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	keypad := NewKeypad()
+	recorder := NewInputRecorder(keypad)
+	frames := map[uint64]func(){
+		2: func() { keypad.Down(1) },
+		4: func() { keypad.Down(0xA) },
+		5: func() { keypad.Up(1) },
+		9: func() { keypad.Up(0xA) },
+	}
+	for frame := uint64(0); frame < 12; frame++ {
+		if f, ok := frames[frame]; ok {
+			f()
+		}
+		recorder.Update(frame)
+		recorder.Update(frame) // Repeated updates for a frame record nothing new
+	}
+
+	var script bytes.Buffer
+	if err := WriteInputScript(&script, recorder.Events); err != nil {
+		t.Fatal(err)
+	}
+	want := "2 1 down\n4 A down\n5 1 up\n9 A up\n"
+	if script.String() != want {
+		t.Fatalf("recorded script:\n%s\nwant:\n%s", script.String(), want)
+	}
+
+	events, err := ReadInputScript(&script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(events, recorder.Events) {
+		t.Errorf("read %v, want %v", events, recorder.Events)
+	}
+
+	replay := NewScriptedInput(events)
+	for frame, want := range map[uint64][2]bool{1: {false, false}, 3: {true, false}, 4: {true, true}, 7: {false, true}, 10: {false, false}} {
+		replay.Update(frame)
+		if got := [2]bool{replay.Pressed(1), replay.Pressed(0xA)}; got != want {
+			t.Errorf("frame %d: keys 1 and A pressed %v, want %v", frame, got, want)
+		}
+	}
+
+	// Going back to frame 4 drops what was recorded after it
+	keypad.Down(1)
+	keypad.Down(0xA)
+	recorder.Update(4)
+	if !reflect.DeepEqual(recorder.Events, events[:2]) {
+		t.Errorf("after going back to frame 4 recorded %v, want %v", recorder.Events, events[:2])
+	}
+}
@@ -0,0 +1,152 @@
+package emulator
+
+import (
+	"strings"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+)
+
+// Platform selects the instruction set and memory layout being emulated
+type Platform int
+
+const (
+	PlatformChip8 Platform = iota
+	PlatformSChip
+	PlatformXOChip
+)
+
+var platformNames = map[Platform]string{
+	PlatformChip8:  "chip8",
+	PlatformSChip:  "schip",
+	PlatformXOChip: "xochip",
+}
+
+func (p Platform) String() string {
+	return platformNames[p]
+}
+
+// Size of the addressable memory in bytes
+func (p Platform) MemorySize() int {
+	if p == PlatformXOChip {
+		return 0x10000
+	}
+	return 0x1000
+}
+
+// Quirk profile the platform's programs are usually written against
+func (p Platform) DefaultQuirks() Quirks {
+	switch p {
+	case PlatformSChip:
+		return QuirksSCHIP
+	case PlatformXOChip:
+		return QuirksXOCHIP
+	}
+	return QuirksVIP
+}
+
+// Looks up a platform by name (case insensitive)
+func PlatformByName(name string) (Platform, bool) {
+	name = strings.ToLower(name)
+	for p, n := range platformNames {
+		if n == name {
+			return p, true
+		}
+	}
+	return PlatformChip8, false
+}
+
+// Guesses the platform a ROM was written for from the instructions it uses:
+// XO-CHIP when it needs more than 4 KiB of memory or uses at least two XO-CHIP
+// instructions, SUPER-CHIP when it uses a SUPER-CHIP one. Only instructions
+// reachable from the start by following jumps, calls and skips are looked at,
+// so sprites and other data don't count. Code only reached through BNNN is
+// missed, which makes the guess err towards the older platform.
+func DetectPlatform(rom []byte) Platform {
+	if len(rom) > PlatformChip8.MemorySize()-0x200 {
+		return PlatformXOChip
+	}
+
+	platform := PlatformChip8
+	xochip := 0
+	seen := make([]bool, len(rom))
+	pending := []int{0}
+	for len(pending) > 0 {
+		i := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for i >= 0 && i+1 < len(rom) && !seen[i] {
+			seen[i] = true
+			op := romOpcode(rom, i)
+			next := i + romInstructionSize(rom, i)
+
+			switch opcodePlatform(op) {
+			case PlatformXOChip:
+				xochip++
+			case PlatformSChip:
+				platform = PlatformSChip
+			}
+
+			switch {
+			case op == 0x00EE, op == 0x00FD, op&0xF000 == 0xB000: // Return, exit, computed jump
+				next = -1
+			case op&0xF000 == 0x1000: // Jump
+				next = int(op&0x0FFF) - 0x200
+			case op&0xF000 == 0x2000: // Call
+				pending = append(pending, int(op&0x0FFF)-0x200)
+			case op&0xF000 == 0x3000, op&0xF000 == 0x4000,
+				op&0xF00F == 0x5000, op&0xF00F == 0x9000,
+				op&0xF0FF == 0xE09E, op&0xF0FF == 0xE0A1: // Skips
+				pending = append(pending, next+romInstructionSize(rom, next))
+			}
+			i = next
+		}
+	}
+
+	if xochip >= 2 {
+		return PlatformXOChip
+	}
+	return platform
+}
+
+// Opcode at offset i of rom, 0 past the end
+func romOpcode(rom []byte, i int) chip8.WORD {
+	if i < 0 || i+1 >= len(rom) {
+		return 0
+	}
+	return chip8.WORD(rom[i])<<8 | chip8.WORD(rom[i+1])
+}
+
+// Size in bytes of the instruction at offset i of rom, F000 is followed by its address
+func romInstructionSize(rom []byte, i int) int {
+	if romOpcode(rom, i) == 0xF000 {
+		return 4
+	}
+	return 2
+}
+
+// Oldest platform that has the instruction
+func opcodePlatform(op chip8.WORD) Platform {
+	switch {
+	case op == 0xF000: // Long I
+		return PlatformXOChip
+	case op == 0xF002: // Audio pattern
+		return PlatformXOChip
+	case op&0xF0FF == 0xF001: // Plane
+		return PlatformXOChip
+	case op&0xF0FF == 0xF03A: // Pitch
+		return PlatformXOChip
+	case op&0xF00F == 0x5002, op&0xF00F == 0x5003: // Save and load register ranges
+		return PlatformXOChip
+	case op&0xFFF0 == 0x00D0: // Scroll up
+		return PlatformXOChip
+	case op == 0x00FE, op == 0x00FF: // Low and high resolution
+		return PlatformSChip
+	case op == 0x00FB, op == 0x00FC, op&0xFFF0 == 0x00C0: // Scroll right, left and down
+		return PlatformSChip
+	case op == 0x00FD: // Exit
+		return PlatformSChip
+	case op&0xF0FF == 0xF030, op&0xF0FF == 0xF075, op&0xF0FF == 0xF085: // Big font, flags
+		return PlatformSChip
+	}
+	return PlatformChip8
+}
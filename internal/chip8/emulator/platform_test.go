@@ -0,0 +1,49 @@
+package emulator
+
+import (
+	"testing"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	tests := []struct {
+		name string
+		rom  []byte
+		want Platform
+	}{
+		{"chip8", []byte{0x60, 0x05, 0xA2, 0x0A, 0xD0, 0x15, 0x12, 0x06}, PlatformChip8},
+		{"hires", []byte{0x00, 0xFF, 0xD0, 0x10}, PlatformSChip},
+		{"big font", []byte{0x60, 0x05, 0xF0, 0x30}, PlatformSChip},
+		{"long I", []byte{0xF0, 0x00, 0x12, 0x34, 0xF3, 0x01}, PlatformXOChip},
+		{"plane and audio", []byte{0xF3, 0x01, 0xF0, 0x02}, PlatformXOChip},
+		{"register range", []byte{0x51, 0x32, 0x51, 0x33}, PlatformXOChip},
+		{"one xochip instruction", []byte{0x00, 0xFF, 0xF3, 0x01}, PlatformSChip},
+		{"long I address", []byte{0xF0, 0x00, 0xF3, 0x01, 0x12, 0x04}, PlatformChip8},
+		{"called", []byte{0x22, 0x04, 0x12, 0x02, 0x00, 0xFF, 0x00, 0xEE}, PlatformSChip},
+		{"skipped", []byte{0x30, 0x01, 0x12, 0x06, 0x00, 0xFF, 0x12, 0x06}, PlatformSChip},
+		{"sprite data", []byte{0xA2, 0x06, 0xD0, 0x12, 0x12, 0x04, 0xF0, 0x00}, PlatformChip8},
+		{"odd length", []byte{0x60, 0x05, 0x00}, PlatformChip8},
+		{"over 4 KiB", make([]byte, 0x1000), PlatformXOChip},
+	}
+	for _, tt := range tests {
+		if got := DetectPlatform(tt.rom); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectPlatformIgnoresData(t *testing.T) {
+	// 0x200: I = 0x206, 0x202: draw it, 0x204: loop, then the sprite. Its rows read
+	// as XO-CHIP and SUPER-CHIP instructions but are never executed
+	code := []byte{0xA2, 0x06, 0xD0, 0x14, 0x12, 0x04}
+	sprites := [][]byte{
+		{0xF0, 0x00, 0xF0, 0x01},
+		{0x00, 0xD1, 0x50, 0x02},
+		{0x00, 0xFF, 0xF0, 0x30},
+	}
+	for _, sprite := range sprites {
+		rom := append(append([]byte{}, code...), sprite...)
+		if got := DetectPlatform(rom); got != PlatformChip8 {
+			t.Errorf("% X: got %s, want chip8", sprite, got)
+		}
+	}
+}
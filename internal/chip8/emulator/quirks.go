@@ -0,0 +1,86 @@
+package emulator
+
+import (
+	"sort"
+	"strings"
+)
+
+// Quirks selects how the emulator interprets the opcodes whose behaviour
+// differs between the original COSMAC VIP interpreter and its descendants.
+type Quirks struct {
+	// 8XY6/8XYE shift VX in place and ignore VY (CHIP-48, SUPER-CHIP).
+	// When false VY is shifted and the result is stored in VX (COSMAC VIP).
+	Shift bool
+
+	// FX55/FX65 leave I pointing past the last register they touched
+	// (COSMAC VIP). When false I is left unchanged (CHIP-48, SUPER-CHIP).
+	LoadStore bool
+
+	// BNNN jumps to XNN plus VX rather than NNN plus V0 (CHIP-48, SUPER-CHIP).
+	Jump bool
+
+	// 8XY1/8XY2/8XY3 reset VF to 0 (COSMAC VIP).
+	VFReset bool
+
+	// Sprites drawn past the edge of the screen are clipped instead of
+	// wrapping around to the other side. The starting coordinate always wraps.
+	Clipping bool
+
+	// DXYN waits for the next timer tick (vertical blank) before execution
+	// continues, limiting programs to one sprite draw per frame (COSMAC VIP).
+	DisplayWait bool
+}
+
+var (
+	// The original COSMAC VIP interpreter
+	QuirksVIP = Quirks{
+		LoadStore:   true,
+		VFReset:     true,
+		Clipping:    true,
+		DisplayWait: true,
+	}
+
+	// CHIP-48 on the HP-48 calculators
+	QuirksCHIP48 = Quirks{
+		Shift:    true,
+		Jump:     true,
+		Clipping: true,
+	}
+
+	// SUPER-CHIP 1.1
+	QuirksSCHIP = Quirks{
+		Shift:    true,
+		Jump:     true,
+		Clipping: true,
+	}
+
+	// XO-CHIP as implemented by Octo
+	QuirksXOCHIP = Quirks{
+		LoadStore: true,
+	}
+)
+
+// QuirkPresets maps the names accepted by QuirksByName to their profiles
+var QuirkPresets = map[string]Quirks{
+	"vip":    QuirksVIP,
+	"chip8":  QuirksVIP,
+	"chip48": QuirksCHIP48,
+	"schip":  QuirksSCHIP,
+	"xochip": QuirksXOCHIP,
+}
+
+// Looks up a quirk preset by name (case insensitive)
+func QuirksByName(name string) (Quirks, bool) {
+	q, ok := QuirkPresets[strings.ToLower(name)]
+	return q, ok
+}
+
+// Returns the sorted names of every quirk preset
+func QuirkPresetNames() []string {
+	names := make([]string, 0, len(QuirkPresets))
+	for name := range QuirkPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,35 @@
+package emulator
+
+// RandSource generates the random numbers drawn by CXNN. Its whole state is a
+// single uint64 so save states can store it and restore it directly.
+type RandSource interface {
+	Uint64() uint64
+	State() uint64
+	SetState(state uint64)
+}
+
+// Creates the default RandSource, a SplitMix64 generator
+func NewRandSource(seed int64) RandSource {
+	return &splitMix64{state: uint64(seed)}
+}
+
+// SplitMix64 (Steele, Lea and Flood), any state is valid
+type splitMix64 struct {
+	state uint64
+}
+
+func (s *splitMix64) Uint64() uint64 {
+	s.state += 0x9E3779B97F4A7C15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+func (s *splitMix64) State() uint64 {
+	return s.state
+}
+
+func (s *splitMix64) SetState(state uint64) {
+	s.state = state
+}
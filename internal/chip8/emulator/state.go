@@ -0,0 +1,187 @@
+package emulator
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+)
+
+// Version of the save state format written by SaveState
+const StateVersion = 1
+
+var stateMagic = [4]byte{'C', 'H', '8', 'S'}
+
+var ErrInvalidState = errors.New("invalid save state")
+
+// Fixed size part of a save state, followed by the memory and screen contents
+type stateHeader struct {
+	Magic   [4]byte
+	Version uint16
+
+	Platform      uint8
+	Quirks        Quirks
+	Registers     [16]chip8.BYTE
+	I             chip8.WORD
+	PC            chip8.WORD
+	StackLen      uint8
+	Stack         [stackSize]chip8.WORD
+	Delay         chip8.BYTE
+	SoundDelay    chip8.BYTE
+	CurrentOpcode chip8.WORD
+	RPLFlags      [16]chip8.BYTE
+	HiRes         bool
+	Exited        bool
+	WaitingForKey bool
+	WaitKey       int8
+	Cycles        uint64
+	Frame         uint64
+	FrameCycle    uint32
+	WaitVBlank    bool
+	Planes        chip8.BYTE
+	AudioPattern  [16]chip8.BYTE
+	Pitch         chip8.BYTE
+	Seed          int64
+	RandState     uint64
+	MemorySize    uint32
+}
+
+// Writes the full machine state to w, including the random generator's state.
+// Only the configuration (ROM, fonts, speed and which random source is used) is
+// left out.
+func (h Emulator) SaveState(w io.Writer) error {
+	header := stateHeader{
+		Magic:         stateMagic,
+		Version:       StateVersion,
+		Platform:      uint8(h.Platform),
+		Quirks:        h.Quirks,
+		Registers:     h.Registers,
+		I:             h.I,
+		PC:            h.PC,
+		StackLen:      uint8(len(h.Stack)),
+		Delay:         h.Delay,
+		SoundDelay:    h.SoundDelay,
+		CurrentOpcode: h.CurrentOpcode,
+		RPLFlags:      h.RPLFlags,
+		HiRes:         h.HiRes,
+		Exited:        h.Exited,
+		WaitingForKey: h.WaitingForKey,
+		WaitKey:       int8(h.waitKey),
+		Cycles:        h.Cycles,
+		Frame:         h.Frame,
+		FrameCycle:    uint32(h.frameCycle),
+		WaitVBlank:    h.waitVBlank,
+		Planes:        h.Planes,
+		AudioPattern:  h.AudioPattern,
+		Pitch:         h.Pitch,
+		Seed:          h.Seed,
+		RandState:     h.rng.State(),
+		MemorySize:    uint32(len(h.Memory)),
+	}
+	copy(header.Stack[:], h.Stack)
+
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, h.Memory); err != nil {
+		return err
+	}
+	width, height := h.Display.Resolution()
+	column := make([]chip8.BYTE, height)
+	for x := 0; x < width; x++ {
+		for y := range column {
+			column[y] = h.Display.Pixel(x, y)
+		}
+		if err := binary.Write(w, binary.BigEndian, column); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restores a machine state written by SaveState. The emulator is left
+// untouched if the state can't be read.
+func (h *Emulator) LoadState(r io.Reader) error {
+	var header stateHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidState, err)
+	}
+	if header.Magic != stateMagic {
+		return fmt.Errorf("%w: bad magic %q", ErrInvalidState, header.Magic[:])
+	}
+	if header.Version != StateVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidState, header.Version)
+	}
+
+	platform := Platform(header.Platform)
+	if _, ok := platformNames[platform]; !ok || int(header.MemorySize) != platform.MemorySize() {
+		return fmt.Errorf("%w: platform %d with %d bytes of memory", ErrInvalidState, header.Platform, header.MemorySize)
+	}
+	if int(header.StackLen) > stackSize {
+		return fmt.Errorf("%w: stack depth %d", ErrInvalidState, header.StackLen)
+	}
+	if header.WaitKey < -1 || header.WaitKey > 0xF {
+		return fmt.Errorf("%w: waiting for key %d", ErrInvalidState, header.WaitKey)
+	}
+	if header.Planes > 3 {
+		return fmt.Errorf("%w: plane mask %d", ErrInvalidState, header.Planes)
+	}
+
+	memory := make([]chip8.BYTE, header.MemorySize)
+	if err := binary.Read(r, binary.BigEndian, memory); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidState, err)
+	}
+
+	// Read the screen before touching the display so a truncated state changes nothing
+	width, height := resolution(header.HiRes)
+	screen := make([][]chip8.BYTE, width)
+	for x := range screen {
+		screen[x] = make([]chip8.BYTE, height)
+		if err := binary.Read(r, binary.BigEndian, screen[x]); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidState, err)
+		}
+	}
+
+	h.Platform = platform
+	h.Quirks = header.Quirks
+	h.Registers = header.Registers
+	h.I = header.I
+	h.PC = header.PC
+	h.Stack = append([]chip8.WORD{}, header.Stack[:header.StackLen]...)
+	h.Delay = header.Delay
+	h.SoundDelay = header.SoundDelay
+	h.updateBuzzer()
+	h.CurrentOpcode = header.CurrentOpcode
+	h.RPLFlags = header.RPLFlags
+	h.HiRes = header.HiRes
+	h.Exited = header.Exited
+	h.WaitingForKey = header.WaitingForKey
+	h.waitKey = int(header.WaitKey)
+	h.Cycles = header.Cycles
+	h.Frame = header.Frame
+	h.frameCycle = int(header.FrameCycle)
+	h.waitVBlank = header.WaitVBlank
+	h.Planes = header.Planes
+	h.AudioPattern = header.AudioPattern
+	h.Pitch = header.Pitch
+	h.Memory = memory
+
+	h.Display.SetResolution(width, height)
+	for x := range screen {
+		for y, pixel := range screen[x] {
+			h.Display.SetPixel(x, y, pixel)
+		}
+	}
+
+	h.Seed = header.Seed
+	h.rng = h.newSource(h.Seed)
+	h.rng.SetState(header.RandState)
+
+	// Debugger stops belong to the execution the state replaced
+	h.skipBreakAt = -1
+	h.watchHit = ""
+	h.target = nil
+	return nil
+}
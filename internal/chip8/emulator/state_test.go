@@ -0,0 +1,162 @@
+package emulator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+)
+
+// Machine state a save state has to restore
+type machineState struct {
+	Memory     []chip8.BYTE
+	Stack      []chip8.WORD
+	Screen     [][]chip8.BYTE
+	Registers  [16]chip8.BYTE
+	PC, I      chip8.WORD
+	Delay      chip8.BYTE
+	SoundDelay chip8.BYTE
+	HiRes      bool
+	Planes     chip8.BYTE
+	Quirks     Quirks
+	RandState  uint64
+}
+
+func captureState(h *Emulator) machineState {
+	width, height := h.Display.Resolution()
+	screen := make([][]chip8.BYTE, width)
+	for x := range screen {
+		screen[x] = make([]chip8.BYTE, height)
+		for y := range screen[x] {
+			screen[x][y] = h.Display.Pixel(x, y)
+		}
+	}
+	return machineState{
+		Memory:     append([]chip8.BYTE{}, h.Memory...),
+		Stack:      append([]chip8.WORD{}, h.Stack...),
+		Screen:     screen,
+		Registers:  h.Registers,
+		PC:         h.PC,
+		I:          h.I,
+		Delay:      h.Delay,
+		SoundDelay: h.SoundDelay,
+		HiRes:      h.HiRes,
+		Planes:     h.Planes,
+		Quirks:     h.Quirks,
+		RandState:  h.rng.State(),
+	}
+}
+
+func TestSaveStateRoundTrip(t *testing.T) {
+	// 0x200: call 0x204, 0x202: loop, 0x204: high resolution, 0x206: V3 = random, 0x208: loop
+	rom := []byte{0x22, 0x04, 0x12, 0x02, 0x00, 0xFF, 0xC3, 0xFF, 0x12, 0x08}
+	h, err := NewEmulatorFromBytes(rom, WithPlatform(PlatformXOChip), WithSeed(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step(t, h, 4)
+	h.I = 0x1234
+	h.Delay = 30
+	h.SoundDelay = 12
+	h.Planes = 3
+	h.Quirks.Jump = !h.Quirks.Jump
+	h.Memory[0xFFFF] = 0xAB
+	h.Display.SetPixel(0, 0, 1)
+	h.Display.SetPixel(127, 63, 2)
+	h.Display.SetPixel(64, 32, 3)
+	want := captureState(h)
+
+	var state bytes.Buffer
+	if err := h.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change everything, and leave debugger stops from before the load
+	step(t, h, 3)
+	if err := h.CPUReset(); err != nil {
+		t.Fatal(err)
+	}
+	h.Registers[0] = 1
+	h.Quirks = Quirks{}
+	h.Memory[0x300] = 0xCD
+	h.Display.SetPixel(1, 1, 3)
+	h.RunTo(0x208)
+	h.skipBreakAt = 0x208
+	h.watchHit = "watchpoint"
+
+	if err := h.LoadState(&state); err != nil {
+		t.Fatal(err)
+	}
+	if got := captureState(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded state differs from the saved one\ngot  %+v\nwant %+v", got, want)
+	}
+	if h.target != nil || h.skipBreakAt != -1 || h.watchHit != "" {
+		t.Errorf("debugger stops kept after loading: target %v, skip 0x%X, watch %q", h.target, h.skipBreakAt, h.watchHit)
+	}
+}
+
+func TestLoadStateRestoresRandomSource(t *testing.T) {
+	// 0x200: CXNN into V0, 0x202: jump back to 0x200
+	h := newTestEmulator(t, Quirks{}, 0xC0FF, 0x1200)
+
+	// Any generator state has to load straight away, without replaying draws
+	h.rng.SetState(^uint64(0) - 1)
+	step(t, h, 1)
+
+	var state bytes.Buffer
+	if err := h.SaveState(&state); err != nil {
+		t.Fatal(err)
+	}
+	saved := state.Bytes()
+
+	draws := func() [4]byte {
+		var v [4]byte
+		for i := range v {
+			step(t, h, 2)
+			v[i] = byte(h.Registers[0])
+		}
+		return v
+	}
+	want := draws()
+
+	if err := h.LoadState(bytes.NewReader(saved)); err != nil {
+		t.Fatal(err)
+	}
+	if got := draws(); got != want {
+		t.Errorf("random numbers after loading %v, want %v", got, want)
+	}
+}
+
+func TestLoadStateRejectsImplausibleValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(header *stateHeader)
+	}{
+		{"stack depth", func(header *stateHeader) { header.StackLen = stackSize + 1 }},
+		{"waiting key", func(header *stateHeader) { header.WaitKey = 16 }},
+		{"planes", func(header *stateHeader) { header.Planes = 4 }},
+		{"memory size", func(header *stateHeader) { header.MemorySize = 0xFFFFFFFF }},
+	}
+	for _, tt := range tests {
+		h := newTestEmulator(t, Quirks{}, 0x1200)
+		header := stateHeader{
+			Magic:      stateMagic,
+			Version:    StateVersion,
+			WaitKey:    -1,
+			Planes:     1,
+			MemorySize: uint32(len(h.Memory)),
+		}
+		tt.modify(&header)
+
+		var state bytes.Buffer
+		if err := binary.Write(&state, binary.BigEndian, header); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.LoadState(&state); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("%s: got %v, want ErrInvalidState", tt.name, err)
+		}
+	}
+}
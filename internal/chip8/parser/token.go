@@ -59,6 +59,17 @@ const (
 	FX33      = "FX33"
 	FX55      = "FX55"
 	FX65      = "FX65"
+
+	// SUPER-CHIP keywords
+	SCD  = "SCD"
+	SCR  = "SCR"
+	SCL  = "SCL"
+	EXIT = "EXIT"
+	LOW  = "LOW"
+	HIGH = "HIGH"
+	FX30 = "FX30"
+	FX75 = "FX75"
+	FX85 = "FX85"
 )
 
 var keywords = map[string]TokenType{
@@ -91,6 +102,15 @@ var keywords = map[string]TokenType{
 	"fx33":      FX33,
 	"fx55":      FX55,
 	"fx65":      FX65,
+	"scd":       SCD,
+	"scr":       SCR,
+	"scl":       SCL,
+	"exit":      EXIT,
+	"low":       LOW,
+	"high":      HIGH,
+	"fx30":      FX30,
+	"fx75":      FX75,
+	"fx85":      FX85,
 }
 
 func LoopupIdent(ident string) TokenType {
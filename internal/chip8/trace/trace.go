@@ -0,0 +1,87 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/disassembler"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+// Tracer logs every instruction an emulator executes, one line each, with the
+// machine state from just before it ran
+type Tracer struct {
+	// Only instructions at addresses from From to To (inclusive) are logged
+	From chip8.WORD
+	To   chip8.WORD
+
+	// When above 0 only the last Limit lines are kept, and they are written on Flush
+	Limit int
+
+	w    *bufio.Writer
+	ring []string
+	next int
+}
+
+func NewTracer(w io.Writer) *Tracer {
+	return &Tracer{
+		To: 0xFFFF,
+		w:  bufio.NewWriter(w),
+	}
+}
+
+// Hooks the tracer into emu, replacing any previous OnExecute hook
+func (t *Tracer) Attach(emu *emulator.Emulator) {
+	emu.OnExecute = func(pc, op chip8.WORD) {
+		t.trace(emu, pc, op)
+	}
+}
+
+func (t *Tracer) trace(emu *emulator.Emulator, pc, op chip8.WORD) {
+	if pc < t.From || pc > t.To {
+		return
+	}
+
+	line := FormatLine(emu, pc, op)
+	if t.Limit <= 0 {
+		t.w.WriteString(line)
+		return
+	}
+
+	if len(t.ring) < t.Limit {
+		t.ring = append(t.ring, line)
+		return
+	}
+	t.ring[t.next] = line
+	t.next = (t.next + 1) % t.Limit
+}
+
+// Writes out any buffered lines. In ring mode the kept lines are written oldest first
+// and the ring is emptied
+func (t *Tracer) Flush() error {
+	for i := range t.ring {
+		t.w.WriteString(t.ring[(t.next+i)%len(t.ring)])
+	}
+	t.ring = t.ring[:0]
+	t.next = 0
+	return t.w.Flush()
+}
+
+// Formats one trace line: address, opcode, disassembly, V0-VF, I and the timers
+func FormatLine(emu *emulator.Emulator, pc, op chip8.WORD) string {
+	// The disassembly already starts with the opcode
+	dis := disassembler.DisassembleOpcode(op)
+	if op == 0xF000 {
+		dis = disassembler.DisassembleAt(emu, pc)
+	}
+	dis = strings.TrimSuffix(dis, "\n")
+
+	regs := make([]string, len(emu.Registers))
+	for i, r := range emu.Registers {
+		regs[i] = fmt.Sprintf("%02X", r)
+	}
+	return fmt.Sprintf("%04X  %-36s V:%s I:%04X DT:%02X ST:%02X\n", pc, dis, strings.Join(regs, " "), emu.I, emu.Delay, emu.SoundDelay)
+}
@@ -0,0 +1,138 @@
+package trace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kctjohnson/chip8-emu/internal/chip8"
+	"github.com/kctjohnson/chip8-emu/internal/chip8/emulator"
+)
+
+// 0x200: V0 = 5, 0x202: V1 += 1, 0x204: jump back to 0x202
+var program = []byte{0x60, 0x05, 0x71, 0x01, 0x12, 0x02}
+
+// Runs program for n instructions under a tracer set up by configure, returning the trace
+func run(t *testing.T, n int, configure func(tr *Tracer)) []string {
+	t.Helper()
+	emu, err := emulator.NewEmulatorFromBytes(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	tr := NewTracer(&out)
+	configure(tr)
+	tr.Attach(emu)
+	for i := 0; i < n; i++ {
+		if err := emu.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.SplitAfter(out.String(), "\n")[:strings.Count(out.String(), "\n")]
+}
+
+func TestRing(t *testing.T) {
+	all := run(t, 10, func(tr *Tracer) {})
+	if len(all) != 10 {
+		t.Fatalf("got %d lines, want 10", len(all))
+	}
+
+	// The ring wraps several times, and is still written oldest first
+	for _, limit := range []int{1, 3, 4, 10, 20} {
+		got := run(t, 10, func(tr *Tracer) { tr.Limit = limit })
+		want := all
+		if limit < len(all) {
+			want = all[len(all)-limit:]
+		}
+		if strings.Join(got, "") != strings.Join(want, "") {
+			t.Errorf("limit %d: got\n%s\nwant\n%s", limit, strings.Join(got, ""), strings.Join(want, ""))
+		}
+	}
+}
+
+func TestRingFlushEmpties(t *testing.T) {
+	emu, err := emulator.NewEmulatorFromBytes(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	tr := NewTracer(&out)
+	tr.Limit = 2
+	tr.Attach(emu)
+	for i := 0; i < 5; i++ {
+		if err := emu.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr.Flush()
+	written := out.Len()
+	tr.Flush()
+	if out.Len() != written {
+		t.Errorf("second flush wrote %q", out.String()[written:])
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		from, to chip8.WORD
+		want     []string // Address of each line
+	}{
+		{0x200, 0x200, []string{"0200"}},
+		{0x202, 0x202, []string{"0202", "0202", "0202"}},
+		{0x202, 0xFFFF, []string{"0202", "0204", "0202", "0204", "0202"}},
+		{0x206, 0xFFFF, nil},
+	}
+	for _, tt := range tests {
+		lines := run(t, 6, func(tr *Tracer) {
+			tr.From = tt.from
+			tr.To = tt.to
+		})
+		var got []string
+		for _, line := range lines {
+			got = append(got, line[:4])
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("0x%04X-0x%04X: got %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestFormatLine(t *testing.T) {
+	emu, err := emulator.NewEmulatorFromBytes(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emu.Registers[0] = 0x05
+	emu.Registers[0xF] = 0xAB
+	emu.I = 0x2F0
+	emu.Delay = 0x3C
+	emu.SoundDelay = 0x01
+
+	got := FormatLine(emu, 0x202, 0x7101)
+	want := "0202  0x7101  ADD reg[0x1], 1              " +
+		"V:05 00 00 00 00 00 00 00 00 00 00 00 00 00 00 AB I:02F0 DT:3C ST:01\n"
+	if got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFormatLineLongI(t *testing.T) {
+	// 0x200: I = 0x1234, an XO-CHIP instruction taking its address from the next word
+	emu, err := emulator.NewEmulatorFromBytes([]byte{0xF0, 0x00, 0x12, 0x34}, emulator.WithPlatform(emulator.PlatformXOChip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	tr := NewTracer(&out)
+	tr.Attach(emu)
+	if err := emu.Step(); err != nil {
+		t.Fatal(err)
+	}
+	tr.Flush()
+	if want := "0200  0xF000  MOV I, 0x1234"; !strings.HasPrefix(out.String(), want) {
+		t.Errorf("got %q, want it to start with %q", out.String(), want)
+	}
+}